@@ -27,6 +27,8 @@ export const wheelVelocitiesToRobotVelocities = (
 /**
  * Convert robot velocities to individual wheel velocities
  * Using differential drive kinematics
+ * Note: the sim engine uses the opposite sign convention (a faster left wheel
+ * turns counterclockwise), so send twistCommand rather than converting here
  */
 export const robotVelocitiesToWheelVelocities = (
   linearVelocity: number,
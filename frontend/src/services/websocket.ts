@@ -14,12 +14,14 @@ export const WS_MESSAGE_TYPES = {
   ERROR: 'error',
   SESSION_CREATED: 'sessionCreated',
   SIMULATION_STATUS: 'simulationStatus',
+  PARTICLES: 'particles',
 } as const;
 
 // Backend state types (matching Go models)
 export interface BackendWheelState {
   velocity: number;
   rotation: number;
+  slip?: number; // ground truth only
 }
 
 export interface BackendRobotState {
@@ -49,18 +51,51 @@ export interface BackendRobotConstants {
   maxSpeed: number;
   maxAccel: number;
   slippageAmount: number;
+  seed?: number;
+}
+
+export interface BackendPoseEstimate {
+  estimator: string;
+  x: number;
+  y: number;
+  theta: number;
+  covariance: number[]; // row-major 3x3 covariance of (x, y, theta)
+}
+
+export interface BackendEncoderReading {
+  leftTicks: number;
+  rightTicks: number;
+  ticksPerRev: number;
+  simTime: number;
 }
 
 export interface StateUpdatePayload {
   groundTruth: BackendRobotState;
   odometry: BackendOdometryEstimate;
+  estimate?: BackendPoseEstimate;
+  encoders?: BackendEncoderReading;
   constants: BackendRobotConstants;
+  simTime: number;
   timestamp: number;
 }
 
+export interface BackendParticle {
+  x: number;
+  y: number;
+  theta: number;
+  weight: number;
+}
+
+export interface ParticlesPayload {
+  total: number;
+  particles: BackendParticle[];
+  simTime: number;
+}
+
 export interface SimulationStatusPayload {
   running: boolean;
   sessionId: string;
+  seed: number;
 }
 
 export interface WSMessage {
@@ -76,6 +111,7 @@ export interface WheelCommandPayload {
 export type WebSocketEventHandler = {
   onStateUpdate?: (payload: StateUpdatePayload) => void;
   onSimulationStatus?: (payload: SimulationStatusPayload) => void;
+  onParticles?: (payload: ParticlesPayload) => void;
   onError?: (code: string, message: string) => void;
   onConnectionChange?: (connected: boolean) => void;
 };
@@ -151,8 +187,8 @@ class WebSocketService {
   }
 
   private handleMessage(message: WSMessage): void {
-    // Log all messages except state updates (too frequent)
-    if (message.type !== WS_MESSAGE_TYPES.STATE_UPDATE) {
+    // Log all messages except state updates and particles (too frequent)
+    if (message.type !== WS_MESSAGE_TYPES.STATE_UPDATE && message.type !== WS_MESSAGE_TYPES.PARTICLES) {
       console.log('Received message:', message.type, message.payload);
     }
     
@@ -162,6 +198,10 @@ class WebSocketService {
         this.handlers.onStateUpdate?.(message.payload as StateUpdatePayload);
         break;
 
+      case WS_MESSAGE_TYPES.PARTICLES:
+        this.handlers.onParticles?.(message.payload as ParticlesPayload);
+        break;
+
       case WS_MESSAGE_TYPES.SIMULATION_STATUS:
         console.log('Received simulationStatus:', message.payload);
         this.handlers.onSimulationStatus?.(message.payload as SimulationStatusPayload);
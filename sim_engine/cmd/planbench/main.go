@@ -0,0 +1,154 @@
+// Command planbench runs the path planners headlessly on a world map and
+// reports how each one performs, so planners can be compared.
+//
+// Usage:
+//
+//	planbench -world map.json -start 0,0,0 -goal 2,-1 -runs 20
+//
+// Each sampling planner run uses its own seed, counting up from -seed, so a
+// benchmark is reproducible. The grid planners are deterministic and run
+// once. With -out, every plan is also written as one JSON object per line.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/planning"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+// Result is one planner run
+type Result struct {
+	Seed     int64               `json:"seed,omitempty"`
+	Elapsed  float64             `json:"elapsed"` // Planning time in seconds
+	Response models.PlanResponse `json:"response"`
+}
+
+func main() {
+	configPath := flag.String("config", "", "robot constants JSON file (defaults are used when empty)")
+	worldPath := flag.String("world", "", "world map JSON file (an empty plane when empty)")
+	startFlag := flag.String("start", "0,0,0", "start pose as x,y[,theta]")
+	goalFlag := flag.String("goal", "", "goal position as x,y")
+	algorithms := flag.String("algorithms", "astar,dijkstra,dstarlite,rrt,rrtstar", "comma-separated planners to run")
+	runs := flag.Int("runs", 10, "runs per sampling planner")
+	seed := flag.Int64("seed", 1, "seed of the first sampling planner run")
+	iterations := flag.Int("iterations", 0, "samples per sampling planner run (0 uses the default)")
+	resolution := flag.Float64("resolution", 0, "grid cell size in meters (0 uses the default)")
+	outPath := flag.String("out", "", "optional JSON lines file of every plan")
+	flag.Parse()
+
+	if *goalFlag == "" {
+		log.Fatal("planbench: -goal is required")
+	}
+	start, err := parsePose(*startFlag)
+	if err != nil {
+		log.Fatalf("planbench: -start: %v", err)
+	}
+	goal, err := parsePose(*goalFlag)
+	if err != nil {
+		log.Fatalf("planbench: -goal: %v", err)
+	}
+
+	constants := models.DefaultRobotConstants()
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			log.Fatalf("planbench: reading config: %v", err)
+		}
+		if err := json.Unmarshal(data, &constants); err != nil {
+			log.Fatalf("planbench: parsing config %s: %v", *configPath, err)
+		}
+		if err := constants.Validate(); err != nil {
+			log.Fatalf("planbench: invalid config %s: %v", *configPath, err)
+		}
+	}
+	var w *world.World
+	if *worldPath != "" {
+		if w, err = world.Load(*worldPath); err != nil {
+			log.Fatalf("planbench: %v", err)
+		}
+	}
+
+	var enc *json.Encoder
+	if *outPath != "" {
+		out, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("planbench: %v", err)
+		}
+		defer out.Close()
+		enc = json.NewEncoder(out)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "algorithm\truns\tfound\tmean length (m)\tmean explored\tmean time (ms)")
+	for _, algorithm := range strings.Split(*algorithms, ",") {
+		req := models.PlanRequest{
+			Start:      start,
+			Goal:       goal,
+			Algorithm:  strings.TrimSpace(algorithm),
+			Resolution: *resolution,
+			Iterations: *iterations,
+		}
+		n := 1
+		if req.Algorithm == models.PlannerRRT || req.Algorithm == models.PlannerRRTStar {
+			n = *runs
+		}
+
+		found, length, explored, elapsed := 0, 0.0, 0, 0.0
+		for i := 0; i < n; i++ {
+			req.Seed = *seed + int64(i)
+			began := time.Now()
+			resp, err := planning.Plan(w, constants, req)
+			if err != nil {
+				log.Fatalf("planbench: %s: %v", req.Algorithm, err)
+			}
+			result := Result{Seed: req.Seed, Elapsed: time.Since(began).Seconds(), Response: resp}
+
+			elapsed += result.Elapsed
+			explored += len(resp.Explored)
+			if resp.Found {
+				found++
+				length += resp.Length
+			}
+			if enc != nil {
+				if err := enc.Encode(result); err != nil {
+					log.Fatalf("planbench: writing %s: %v", *outPath, err)
+				}
+			}
+		}
+
+		meanLength := "-"
+		if found > 0 {
+			meanLength = strconv.FormatFloat(length/float64(found), 'f', 3, 64)
+		}
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\t%d\t%.1f\n", req.Algorithm, n, found, meanLength,
+			explored/n, elapsed/float64(n)*1000)
+	}
+	table.Flush()
+}
+
+// parsePose parses "x,y" or "x,y,theta"
+func parsePose(s string) (models.Pose, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) < 2 || len(fields) > 3 {
+		return models.Pose{}, fmt.Errorf("want x,y or x,y,theta, got %q", s)
+	}
+	values := make([]float64, 3)
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return models.Pose{}, fmt.Errorf("invalid number %q", f)
+		}
+		values[i] = v
+	}
+	return models.Pose{X: values[0], Y: values[1], Theta: values[2]}, nil
+}
@@ -6,14 +6,35 @@ import (
 	"os"
 
 	"github.com/amogh1216/robot-vis/sim_engine/internal/api"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/storage"
 	"github.com/amogh1216/robot-vis/sim_engine/internal/websocket"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 )
 
 func main() {
+	// Open session store
+	dbPath := getEnv("DB_PATH", "sim_engine.db")
+	store, err := storage.Open(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open session store: %v", err)
+	}
+	defer store.Close()
+	log.Printf("Recording sessions to %s", dbPath)
+
 	// Initialize WebSocket hub
-	hub := websocket.NewHub()
+	hub := websocket.NewHub(store)
+
+	// Load the initial world map, if configured
+	if worldFile := getEnv("WORLD_FILE", ""); worldFile != "" {
+		w, err := world.Load(worldFile)
+		if err != nil {
+			log.Fatalf("Failed to load world: %v", err)
+		}
+		hub.SetWorld(w)
+		log.Printf("Loaded world from %s", worldFile)
+	}
 	go hub.Run()
 
 	// Set up router
@@ -24,6 +45,11 @@ func main() {
 	apiRouter := router.PathPrefix("/api").Subrouter()
 	apiRouter.HandleFunc("/health", apiHandler.HealthCheck).Methods("GET")
 	apiRouter.HandleFunc("/constants", apiHandler.UpdateConstants).Methods("POST")
+	apiRouter.HandleFunc("/twist", apiHandler.SetTwist).Methods("POST")
+	apiRouter.HandleFunc("/plan", apiHandler.Plan).Methods("POST")
+	apiRouter.HandleFunc("/rooms", apiHandler.ListRooms).Methods("GET")
+	apiRouter.HandleFunc("/sessions", apiHandler.ListSessions).Methods("GET")
+	apiRouter.HandleFunc("/sessions/{id}/trajectory", apiHandler.GetTrajectory).Methods("GET")
 
 	// WebSocket route
 	router.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,325 @@
+// Command simrun steps the simulation engine headlessly, as fast as the CPU
+// allows, and writes the resulting ground-truth/odometry trajectory to a file.
+//
+// Usage:
+//
+//	simrun -config robot.json -script commands.json -out trajectory.csv
+//
+// An optional -world file adds obstacles in the same JSON format as the
+// loadWorld message.
+//
+// The robot config is a RobotConstants JSON object. The script is a JSON array
+// of timestamped wheel commands, each held until the next one:
+//
+//	[{"time": 0, "leftVelocity": 10, "rightVelocity": 10},
+//	 {"time": 2.5, "leftVelocity": 5, "rightVelocity": -5}]
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/simulation"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+// TimedWheelCommand is a wheel command that takes effect at a simulated time
+type TimedWheelCommand struct {
+	Time float64 `json:"time"` // Simulated time in seconds
+	models.WheelCommand
+}
+
+// Sample is one row of the written trajectory
+type Sample struct {
+	Time        float64                 `json:"time"`
+	Command     models.WheelCommand     `json:"command"`
+	GroundTruth models.RobotState       `json:"groundTruth"`
+	Odometry    models.OdometryEstimate `json:"odometry"`
+	Estimate    *models.PoseEstimate    `json:"estimate,omitempty"`
+	Encoders    *models.EncoderReading  `json:"encoders,omitempty"`
+	PID         *models.WheelPIDState   `json:"pid,omitempty"`
+	Tracking    *models.TrackingError   `json:"tracking,omitempty"`
+}
+
+func main() {
+	configPath := flag.String("config", "", "robot constants JSON file (defaults are used when empty)")
+	worldPath := flag.String("world", "", "world map JSON file (an empty plane when empty)")
+	scriptPath := flag.String("script", "", "timestamped wheel command script (JSON)")
+	outPath := flag.String("out", "trajectory.csv", "output file; .csv or .jsonl")
+	dt := flag.Float64("dt", 1.0/120.0, "simulation time step in seconds")
+	duration := flag.Float64("duration", 0, "simulated seconds to run (defaults to the last command time + 1s)")
+	seed := flag.Int64("seed", 0, "noise stream seed (overrides the config; 0 keeps the config seed)")
+	commandTimeout := flag.Float64("command-timeout", 0, "stop the wheels after this many seconds without a command (0 disables the watchdog)")
+	flag.Parse()
+
+	if *scriptPath == "" {
+		log.Fatal("simrun: -script is required")
+	}
+	if *dt <= 0 {
+		log.Fatal("simrun: -dt must be positive")
+	}
+
+	engine := simulation.NewEngine()
+	if *configPath != "" {
+		constants, err := loadConstants(*configPath)
+		if err != nil {
+			log.Fatalf("simrun: %v", err)
+		}
+		engine.UpdateConstants(constants)
+	}
+	if *seed != 0 {
+		engine.SetSeed(*seed)
+	}
+	if *worldPath != "" {
+		w, err := world.Load(*worldPath)
+		if err != nil {
+			log.Fatalf("simrun: %v", err)
+		}
+		engine.SetWorld(w)
+	}
+	engine.SetCommandTimeout(*commandTimeout)
+	engine.Reset()
+
+	script, err := loadScript(*scriptPath)
+	if err != nil {
+		log.Fatalf("simrun: %v", err)
+	}
+
+	total := *duration
+	if total <= 0 {
+		total = 1
+		if len(script) > 0 {
+			total += script[len(script)-1].Time
+		}
+	}
+
+	steps, events, err := writeTrajectory(*outPath, engine, script, *dt, total)
+	if err != nil {
+		log.Fatalf("simrun: %v", err)
+	}
+
+	log.Printf("simrun: %d steps (%.3fs simulated, seed %d) written to %s",
+		steps, engine.SimTime, engine.Seed(), *outPath)
+	for eventType, count := range events {
+		log.Printf("simrun: %d %s events", count, eventType)
+	}
+}
+
+// writeTrajectory runs the script and writes every sample to path. The file
+// is flushed and closed before it returns, so callers may exit on an error.
+func writeTrajectory(path string, engine *simulation.Engine, script []TimedWheelCommand, dt, duration float64) (int, map[string]int, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	writer := newSampleWriter(w, filepath.Ext(path))
+
+	steps, events, err := run(engine, script, dt, duration, writer.Write)
+	if err != nil {
+		return steps, events, fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := writer.Flush(); err != nil {
+		return steps, events, fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		return steps, events, fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := out.Close(); err != nil {
+		return steps, events, fmt.Errorf("closing %s: %w", path, err)
+	}
+	return steps, events, nil
+}
+
+// timeEpsilon absorbs floating point drift in the accumulated simulation time
+const timeEpsilon = 1e-9
+
+// run steps the engine through the script and calls emit after every step.
+// It returns the number of steps taken and a count of engine events by type,
+// stopping at the first error from emit.
+func run(engine *simulation.Engine, script []TimedWheelCommand, dt, duration float64, emit func(Sample) error) (int, map[string]int, error) {
+	events := make(map[string]int)
+	steps := int(math.Ceil(duration/dt - timeEpsilon))
+	next := 0
+	for i := 0; i < steps; i++ {
+		// Apply every command that is due at the start of this step
+		for next < len(script) && script[next].Time <= engine.SimTime+timeEpsilon {
+			engine.SetWheelCommand(script[next].WheelCommand)
+			next++
+		}
+
+		engine.Step(dt)
+		for _, event := range engine.DrainEvents() {
+			events[event.Type]++
+		}
+
+		gt, odom := engine.GetState()
+		if err := emit(Sample{
+			Time:        engine.SimTime,
+			Command:     engine.WheelCommand,
+			GroundTruth: gt,
+			Odometry:    odom,
+			Estimate:    engine.Estimate(),
+			Encoders:    engine.Encoders(),
+			PID:         engine.PIDState(),
+			Tracking:    engine.Tracking(),
+		}); err != nil {
+			return i, events, err
+		}
+	}
+	return steps, events, nil
+}
+
+// loadConstants reads a RobotConstants JSON file on top of the defaults
+func loadConstants(path string) (models.RobotConstants, error) {
+	constants := models.DefaultRobotConstants()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return constants, fmt.Errorf("reading config: %w", err)
+	}
+	if err := json.Unmarshal(data, &constants); err != nil {
+		return constants, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := constants.Validate(); err != nil {
+		return constants, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return constants, nil
+}
+
+// loadScript reads a wheel command script sorted by time
+func loadScript(path string) ([]TimedWheelCommand, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading script: %w", err)
+	}
+
+	var script []TimedWheelCommand
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parsing script %s: %w", path, err)
+	}
+
+	sort.SliceStable(script, func(i, j int) bool {
+		return script[i].Time < script[j].Time
+	})
+	return script, nil
+}
+
+// sampleWriter writes trajectory samples in a single output format
+type sampleWriter interface {
+	Write(Sample) error
+	Flush() error
+}
+
+func newSampleWriter(w io.Writer, ext string) sampleWriter {
+	if ext == ".jsonl" || ext == ".json" {
+		return &jsonlWriter{enc: json.NewEncoder(w)}
+	}
+	return newCSVWriter(w)
+}
+
+// jsonlWriter writes one JSON object per line
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(s Sample) error {
+	return j.enc.Encode(s)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return nil
+}
+
+// csvWriter writes a flat CSV row per sample
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+var csvHeader = []string{
+	"time", "cmdLeft", "cmdRight",
+	"trueX", "trueY", "trueTheta", "trueLinearVel", "trueAngularVel",
+	"trueLeftWheelVel", "trueRightWheelVel",
+	"odomX", "odomY", "odomTheta", "odomLinearVel", "odomAngularVel",
+	"estX", "estY", "estTheta",
+	"leftTicks", "rightTicks",
+	"leftSetpoint", "leftMeasured", "leftEffort",
+	"rightSetpoint", "rightMeasured", "rightEffort",
+	"crossTrackError", "headingError",
+}
+
+func (c *csvWriter) Write(s Sample) error {
+	if !c.wroteHeader {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+
+	gt, odom := s.GroundTruth, s.Odometry
+	row := formatFloats(
+		s.Time, s.Command.LeftVelocity, s.Command.RightVelocity,
+		gt.X, gt.Y, gt.Theta, gt.LinearVel, gt.AngularVel,
+		gt.LeftWheel.Velocity, gt.RightWheel.Velocity,
+		odom.X, odom.Y, odom.Theta, odom.LinearVel, odom.AngularVel,
+	)
+	if s.Estimate != nil {
+		row = append(row, formatFloats(s.Estimate.X, s.Estimate.Y, s.Estimate.Theta)...)
+	} else {
+		row = append(row, "", "", "")
+	}
+	if s.Encoders != nil {
+		row = append(row,
+			strconv.FormatInt(s.Encoders.LeftTicks, 10),
+			strconv.FormatInt(s.Encoders.RightTicks, 10))
+	} else {
+		row = append(row, "", "")
+	}
+	if p := s.PID; p != nil {
+		row = append(row, formatFloats(
+			p.Left.Setpoint, p.Left.Measured, p.Left.Effort,
+			p.Right.Setpoint, p.Right.Measured, p.Right.Effort,
+		)...)
+	} else {
+		row = append(row, "", "", "", "", "", "")
+	}
+	if t := s.Tracking; t != nil {
+		row = append(row, formatFloats(t.CrossTrack, t.HeadingError)...)
+	} else {
+		row = append(row, "", "")
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formatFloats formats values with the shortest exact representation so that
+// identical runs produce byte-identical files
+func formatFloats(values ...float64) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return out
+}
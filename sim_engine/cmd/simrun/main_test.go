@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/simulation"
+)
+
+var testScript = []TimedWheelCommand{
+	{Time: 0, WheelCommand: models.WheelCommand{LeftVelocity: 10, RightVelocity: 10}},
+	{Time: 0.5, WheelCommand: models.WheelCommand{LeftVelocity: 5, RightVelocity: -5}},
+}
+
+func TestWriteTrajectoryReproducible(t *testing.T) {
+	for _, ext := range []string{".csv", ".jsonl"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := t.TempDir()
+			var outputs [2][]byte
+			for i := range outputs {
+				engine := simulation.NewEngineWithSeed(11)
+				engine.Reset()
+
+				path := filepath.Join(dir, "run"+string(rune('a'+i))+ext)
+				steps, _, err := writeTrajectory(path, engine, testScript, 1.0/120, 1)
+				if err != nil {
+					t.Fatalf("writeTrajectory: %v", err)
+				}
+				if steps != 120 {
+					t.Errorf("steps = %d, want 120", steps)
+				}
+
+				outputs[i], err = os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(outputs[0]) == 0 {
+				t.Fatal("empty trajectory")
+			}
+			if !bytes.Equal(outputs[0], outputs[1]) {
+				t.Error("runs with the same seed wrote different files")
+			}
+		})
+	}
+}
+
+func TestRunStopsOnEmitError(t *testing.T) {
+	engine := simulation.NewEngineWithSeed(11)
+	engine.Reset()
+
+	errFull := errors.New("disk full")
+	calls := 0
+	steps, _, err := run(engine, testScript, 1.0/120, 1, func(Sample) error {
+		calls++
+		if calls == 3 {
+			return errFull
+		}
+		return nil
+	})
+	if !errors.Is(err, errFull) {
+		t.Fatalf("err = %v, want %v", err, errFull)
+	}
+	if calls != 3 || steps != 2 {
+		t.Errorf("calls = %d, steps = %d; want 3 calls and 2 completed steps", calls, steps)
+	}
+}
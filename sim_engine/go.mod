@@ -7,4 +7,7 @@ require (
 	github.com/gorilla/mux v1.8.1
 	github.com/gorilla/websocket v1.5.3
 	github.com/rs/cors v1.11.1
+	go.etcd.io/bbolt v1.4.0
 )
+
+require golang.org/x/sys v0.29.0 // indirect
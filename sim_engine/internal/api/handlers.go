@@ -2,10 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/simulation"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/storage"
 	"github.com/amogh1216/robot-vis/sim_engine/internal/websocket"
+	"github.com/gorilla/mux"
 )
 
 // Handler handles API requests
@@ -26,6 +31,7 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		"status":  "ok",
 		"service": "robot-simulation-engine",
 		"running": h.hub.IsRunning(),
+		"rooms":   len(h.hub.Rooms()),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -41,15 +47,146 @@ func (h *Handler) UpdateConstants(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate constants
-	if constants.WheelBase <= 0 || constants.WheelRadius <= 0 {
-		http.Error(w, "Invalid constants: wheelBase and wheelRadius must be positive", http.StatusBadRequest)
+	if err := constants.Validate(); err != nil {
+		http.Error(w, "Invalid constants: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	room, ok := h.undrivenRoom(w, r)
+	if !ok {
 		return
 	}
 
 	// Update engine constants
-	engine := h.hub.GetEngine()
-	engine.UpdateConstants(constants)
+	if err := room.UpdateConstants(r.URL.Query().Get("robotId"), constants); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 }
+
+// SetTwist drives the robot with a linear/angular velocity command and
+// returns the wheel command it was converted to
+func (h *Handler) SetTwist(w http.ResponseWriter, r *http.Request) {
+	var twist models.TwistCommand
+	if err := json.NewDecoder(r.Body).Decode(&twist); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	room, ok := h.undrivenRoom(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := room.SetTwistCommand(r.URL.Query().Get("robotId"), twist)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Plan returns a collision-free path between two poses on the current world
+// map, with the cells the search explored
+func (h *Handler) Plan(w http.ResponseWriter, r *http.Request) {
+	var req models.PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	room, ok := h.room(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := room.Plan(r.URL.Query().Get("robotId"), req)
+	if errors.Is(err, simulation.ErrUnknownRobot) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid plan request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListRooms returns the open simulation rooms
+func (h *Handler) ListRooms(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.hub.Rooms())
+}
+
+// room resolves the room query parameter, writing a 404 when no such room
+// is open. Requests without one address the default room.
+func (h *Handler) room(w http.ResponseWriter, r *http.Request) (*websocket.Room, bool) {
+	room, err := h.hub.Room(r.URL.Query().Get("room"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return room, true
+}
+
+// undrivenRoom resolves the room like room, and writes a 409 when a client
+// has control of it. Only the driver may change a driven room's robots, so
+// REST changes would fight its commands.
+func (h *Handler) undrivenRoom(w http.ResponseWriter, r *http.Request) (*websocket.Room, bool) {
+	room, ok := h.room(w, r)
+	if ok && room.HasDriver() {
+		http.Error(w, websocket.ErrRoomDriven.Error(), http.StatusConflict)
+		return nil, false
+	}
+	return room, ok
+}
+
+// ListSessions returns all recorded sessions, newest first
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	store := h.hub.GetStore()
+	if store == nil {
+		http.Error(w, "Session storage is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	sessions, err := store.ListSessions()
+	if err != nil {
+		log.Printf("Error listing sessions: %v", err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// GetTrajectory returns the recorded trajectory of a session
+func (h *Handler) GetTrajectory(w http.ResponseWriter, r *http.Request) {
+	store := h.hub.GetStore()
+	if store == nil {
+		http.Error(w, "Session storage is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	points, err := store.Trajectory(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error reading trajectory for session %s: %v", id, err)
+		http.Error(w, "Failed to read trajectory", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(points)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/websocket"
+	gorilla "github.com/gorilla/websocket"
+)
+
+func TestRESTChangesConflictWithDriver(t *testing.T) {
+	hub := websocket.NewHub(nil)
+	go hub.Run()
+	handler := NewHandler(hub)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		websocket.ServeWs(hub, w, r)
+	})
+	mux.HandleFunc("/api/twist", handler.SetTwist)
+	mux.HandleFunc("/api/constants", handler.UpdateConstants)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	post := func(path, body string) int {
+		t.Helper()
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	const twist = `{"linearVel": 0.2}`
+	const constants = `{"wheelBase": 0.2, "wheelRadius": 0.05, "maxSpeed": 1, "maxAccel": 1}`
+
+	// Nobody has joined the default room, so nobody drives it
+	if code := post("/api/twist", twist); code != http.StatusOK {
+		t.Errorf("twist without a driver: status %d, want 200", code)
+	}
+
+	// The first client in a room takes control of it
+	conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?room=lab", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("reading roomJoined: %v", err)
+	}
+
+	if code := post("/api/twist?room=lab", twist); code != http.StatusConflict {
+		t.Errorf("twist with a driver: status %d, want 409", code)
+	}
+	if code := post("/api/constants?room=lab", constants); code != http.StatusConflict {
+		t.Errorf("constants with a driver: status %d, want 409", code)
+	}
+	if code := post("/api/constants?room=missing", constants); code != http.StatusNotFound {
+		t.Errorf("constants for a missing room: status %d, want 404", code)
+	}
+}
@@ -0,0 +1,103 @@
+package models
+
+import "errors"
+
+// Estimator names accepted in RobotConstants.Estimator
+const (
+	EstimatorEKF            = "ekf"
+	EstimatorParticleFilter = "pf"
+	EstimatorNone           = "none"
+)
+
+// PoseEstimate is a filtered pose estimate with its uncertainty
+type PoseEstimate struct {
+	Estimator  string     `json:"estimator"` // Name of the estimator that produced it
+	X          float64    `json:"x"`
+	Y          float64    `json:"y"`
+	Theta      float64    `json:"theta"`
+	Covariance [9]float64 `json:"covariance"` // Row-major 3x3 covariance of (x, y, theta)
+}
+
+// EKFConfig holds the noise model of the Extended Kalman Filter
+type EKFConfig struct {
+	LinearVelNoise  float64 `json:"linearVelNoise"`  // Std dev of linear velocity error, as a fraction of speed
+	AngularVelNoise float64 `json:"angularVelNoise"` // Std dev of angular velocity error, as a fraction of turn rate
+	FuseGyro        bool    `json:"fuseGyro"`        // Predict heading with the IMU yaw rate instead of the encoders
+}
+
+// ParticleFilterConfig holds the particle count and noise model of the
+// Monte Carlo localization filter
+type ParticleFilterConfig struct {
+	NumParticles int `json:"numParticles"`
+
+	// Motion noise of the velocity motion model (Probabilistic Robotics α1-α4):
+	// std of v error = α1|v| + α2|ω|, std of ω error = α3|v| + α4|ω|
+	Alpha1 float64 `json:"alpha1"`
+	Alpha2 float64 `json:"alpha2"`
+	Alpha3 float64 `json:"alpha3"`
+	Alpha4 float64 `json:"alpha4"`
+
+	RangeNoiseStd        float64 `json:"rangeNoiseStd"`        // Std dev of a range measurement in meters
+	MaxBeams             int     `json:"maxBeams"`             // Beams of each scan used for weighting
+	ResampleThreshold    float64 `json:"resampleThreshold"`    // Resample when effective sample size < threshold * N
+	InitialSpread        float64 `json:"initialSpread"`        // Std dev of the initial position spread in meters
+	InitialHeadingSpread float64 `json:"initialHeadingSpread"` // Std dev of the initial heading spread in radians
+	BroadcastCount       int     `json:"broadcastCount"`       // Particles included in each particles message
+}
+
+// Validate checks the EKF noise model
+func (c EKFConfig) Validate() error {
+	if !nonNegative(c.LinearVelNoise, c.AngularVelNoise) {
+		return errors.New("ekf noise must be at least 0")
+	}
+	return nil
+}
+
+// Validate checks the particle filter configuration
+func (c ParticleFilterConfig) Validate() error {
+	switch {
+	case c.NumParticles <= 0:
+		return errors.New("particleFilter numParticles must be positive")
+	case c.MaxBeams < 0 || c.BroadcastCount < 0:
+		return errors.New("particleFilter maxBeams and broadcastCount must be at least 0")
+	case !nonNegative(c.Alpha1, c.Alpha2, c.Alpha3, c.Alpha4, c.RangeNoiseStd, c.InitialSpread, c.InitialHeadingSpread):
+		return errors.New("particleFilter noise and spreads must be at least 0")
+	case !(c.ResampleThreshold >= 0 && c.ResampleThreshold <= 1):
+		return errors.New("particleFilter resampleThreshold must be between 0 and 1")
+	}
+	return nil
+}
+
+// Particle is a single weighted pose hypothesis
+type Particle struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Theta  float64 `json:"theta"`
+	Weight float64 `json:"weight"`
+}
+
+// DefaultEKFConfig returns the default EKF noise model
+func DefaultEKFConfig() EKFConfig {
+	return EKFConfig{
+		LinearVelNoise:  0.05,
+		AngularVelNoise: 0.05,
+		FuseGyro:        true,
+	}
+}
+
+// DefaultParticleFilterConfig returns the default particle filter
+func DefaultParticleFilterConfig() ParticleFilterConfig {
+	return ParticleFilterConfig{
+		NumParticles:         500,
+		Alpha1:               0.1,
+		Alpha2:               0.02,
+		Alpha3:               0.05,
+		Alpha4:               0.1,
+		RangeNoiseStd:        0.1,
+		MaxBeams:             30,
+		ResampleThreshold:    0.5,
+		InitialSpread:        0.05,
+		InitialHeadingSpread: 0.05,
+		BroadcastCount:       200,
+	}
+}
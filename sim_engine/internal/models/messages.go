@@ -6,9 +6,25 @@ type WheelCommand struct {
 	RightVelocity float64 `json:"rightVelocity"` // Right wheel angular velocity in rad/s
 }
 
+// TwistCommand is a robot velocity command, converted to wheel velocities by
+// the engine
+type TwistCommand struct {
+	LinearVel  float64 `json:"linearVel"`  // Forward speed in m/s
+	AngularVel float64 `json:"angularVel"` // Turn rate in rad/s, counter-clockwise positive
+}
+
+// TwistResult reports how a twist command was applied
+type TwistResult struct {
+	Requested    TwistCommand `json:"requested"`
+	Applied      TwistCommand `json:"applied"`      // Twist after the speed limits
+	Limited      bool         `json:"limited"`      // Whether the limits changed the twist
+	WheelCommand WheelCommand `json:"wheelCommand"` // Resulting wheel velocities
+}
+
 // WSMessage is the generic WebSocket message structure
 type WSMessage struct {
 	Type    string      `json:"type"`
+	RobotID string      `json:"robotId,omitempty"` // Robot a command is for or an event came from; empty means the first robot
 	Payload interface{} `json:"payload,omitempty"`
 }
 
@@ -20,20 +36,73 @@ const (
 	MsgTypeStartSimulation = "startSimulation"
 	MsgTypeStopSimulation  = "stopSimulation"
 	MsgTypeResetSimulation = "resetSimulation"
+	MsgTypeReplaySession   = "replaySession"
+	MsgTypeReplayControl   = "replayControl"
+	MsgTypeLoadWorld       = "loadWorld"
+	MsgTypeMotorCommand    = "motorCommand"
+	MsgTypeTwistCommand    = "twistCommand"
+	MsgTypeNavigateTo      = "navigateTo"
+	MsgTypeFollowPath      = "followPath"
+	MsgTypeDWA             = "dwa"
+	MsgTypeCancelControl   = "cancelControl"
+	MsgTypeAddRobot        = "addRobot"
+	MsgTypeRemoveRobot     = "removeRobot"
+	MsgTypeCreateRoom      = "createRoom"
+	MsgTypeJoinRoom        = "joinRoom"
+	MsgTypeRequestControl  = "requestControl"
+	MsgTypeHandOverControl = "handOverControl"
+
+	// Client -> Server, simulation clock
+	MsgTypePauseSimulation  = "pauseSimulation"
+	MsgTypeResumeSimulation = "resumeSimulation"
+	MsgTypeStepOnce         = "stepOnce"
+	MsgTypeSetTimeScale     = "setTimeScale"
 
 	// Server -> Client
 	MsgTypeStateUpdate      = "stateUpdate"
 	MsgTypeError            = "error"
 	MsgTypeSessionCreated   = "sessionCreated"
 	MsgTypeSimulationStatus = "simulationStatus"
+	MsgTypeReplayStatus     = "replayStatus"
+	MsgTypeParticles        = "particles"
+	MsgTypeWorld            = "world"
+	MsgTypeCollision        = "collision"
+	MsgTypeLaserScan        = "laserScan"
+	MsgTypeIMU              = "imu"
+	MsgTypeGNSS             = "gnss"
+	MsgTypeTwistResult      = "twistResult"
+	MsgTypeNavigation       = "navigation"
+	MsgTypeDWAArcs          = "dwaArcs"
+	MsgTypeRobotCollision   = "robotCollision"
+	MsgTypeRoomJoined       = "roomJoined"
+	MsgTypeControlRequested = "controlRequested"
+	MsgTypeWatchdogTripped  = "watchdogTripped"
 )
 
-// StateUpdatePayload is sent to clients with current state
+// Replay control actions
+const (
+	ReplayActionPause  = "pause"
+	ReplayActionResume = "resume"
+	ReplayActionSeek   = "seek"
+	ReplayActionSpeed  = "speed"
+	ReplayActionStop   = "stop"
+)
+
+// StateUpdatePayload is sent to clients with current state. The top-level
+// fields describe the first robot; Robots holds every robot, each with its ID.
 type StateUpdatePayload struct {
+	RobotID     string           `json:"robotId,omitempty"`
 	GroundTruth RobotState       `json:"groundTruth"`
 	Odometry    OdometryEstimate `json:"odometry"`
+	Estimate    *PoseEstimate    `json:"estimate,omitempty"` // Filtered estimate, when an estimator is active
+	Encoders    *EncoderReading  `json:"encoders,omitempty"` // Raw wheel encoder counters
+	PID         *WheelPIDState   `json:"pid,omitempty"`      // Wheel velocity loops, while they are running
+	Tracking    *TrackingError   `json:"tracking,omitempty"` // Path tracking error, while following a path
 	Constants   RobotConstants   `json:"constants"`
+	SimTime     float64          `json:"simTime"`   // Simulated time in seconds
 	Timestamp   int64            `json:"timestamp"` // Unix timestamp ms
+
+	Robots []StateUpdatePayload `json:"robots,omitempty"`
 }
 
 // ErrorPayload contains error information
@@ -42,8 +111,103 @@ type ErrorPayload struct {
 	Message string `json:"message"`
 }
 
+// StartSimulationPayload optionally seeds the noise stream of a new run
+type StartSimulationPayload struct {
+	Seed           int64   `json:"seed,omitempty"`           // 0 keeps the engine's current seed
+	CommandTimeout float64 `json:"commandTimeout,omitempty"` // Command watchdog timeout in wall-clock seconds, 0 when disabled
+	TargetFPS      int     `json:"targetFps,omitempty"`      // Loop iterations per second of wall time; 0 keeps the current rate
+	Dt             float64 `json:"dt,omitempty"`             // Simulation time step in seconds; 0 keeps the current step
+}
+
+// StepOncePayload advances a paused or stopped simulation by a single step
+type StepOncePayload struct {
+	Dt float64 `json:"dt,omitempty"` // Step length in seconds; 0 uses the session time step
+}
+
+// TimeScalePayload sets how fast simulated time runs relative to wall time
+type TimeScalePayload struct {
+	Scale float64 `json:"scale"` // 0.1 to 10
+}
+
+// WatchdogPayload is broadcast when a robot is stopped because its commands
+// stopped arriving
+type WatchdogPayload struct {
+	Timeout     float64 `json:"timeout"`     // Command watchdog timeout in wall-clock seconds
+	LastCommand float64 `json:"lastCommand"` // Simulated time of the last command
+	SimTime     float64 `json:"simTime"`
+}
+
 // SimulationStatusPayload indicates if simulation is running
 type SimulationStatusPayload struct {
+	RoomID    string `json:"roomId"`
 	Running   bool   `json:"running"`
 	SessionID string `json:"sessionId"`
+	Seed      int64  `json:"seed"`
+	Driver    string `json:"driver"` // Client ID of the driver, empty when nobody has control
+
+	Paused    bool    `json:"paused"`
+	TimeScale float64 `json:"timeScale"` // Simulated seconds per wall-clock second
+	TargetFPS int     `json:"targetFps"` // Loop iterations per second of wall time
+	Dt        float64 `json:"dt"`        // Simulation time step in seconds
+}
+
+// RoomPayload names a room to create or join
+type RoomPayload struct {
+	RoomID string `json:"roomId,omitempty"` // Generated when creating a room without one
+}
+
+// RoomInfo describes a simulation room
+type RoomInfo struct {
+	RoomID    string `json:"roomId"`
+	Clients   int    `json:"clients"` // Clients currently in the room
+	Running   bool   `json:"running"`
+	SessionID string `json:"sessionId,omitempty"`
+	Driver    string `json:"driver,omitempty"` // Client ID of the driver
+}
+
+// RoomJoinedPayload tells a client the room it is in and its own client ID
+type RoomJoinedPayload struct {
+	RoomInfo
+	ClientID string `json:"clientId"`
+}
+
+// HandOverControlPayload passes control of a room to another client
+type HandOverControlPayload struct {
+	ClientID string `json:"clientId,omitempty"` // Empty releases control to nobody
+}
+
+// ControlRequestedPayload tells the driver that a client asked for control
+type ControlRequestedPayload struct {
+	ClientID string `json:"clientId"`
+}
+
+// ReplaySessionPayload starts streaming a recorded session
+type ReplaySessionPayload struct {
+	SessionID string  `json:"sessionId"`
+	Speed     float64 `json:"speed,omitempty"` // Playback speed multiplier (default 1)
+}
+
+// ReplayControlPayload controls an active replay
+type ReplayControlPayload struct {
+	Action string  `json:"action"`          // pause, resume, seek, speed or stop
+	Time   float64 `json:"time,omitempty"`  // Seek target in seconds from the start
+	Speed  float64 `json:"speed,omitempty"` // New speed multiplier
+}
+
+// ReplayStatusPayload reports the state of a replay
+type ReplayStatusPayload struct {
+	SessionID string  `json:"sessionId"`
+	Active    bool    `json:"active"`
+	Paused    bool    `json:"paused"`
+	Finished  bool    `json:"finished"` // Reached the last frame; resume plays it again
+	Speed     float64 `json:"speed"`
+	Position  float64 `json:"position"` // Seconds from the start of the recording
+	Duration  float64 `json:"duration"` // Length of the recording in seconds
+}
+
+// ParticlesPayload is a downsampled particle set of the particle filter
+type ParticlesPayload struct {
+	Total     int        `json:"total"` // Particles in the filter
+	Particles []Particle `json:"particles"`
+	SimTime   float64    `json:"simTime"`
 }
@@ -0,0 +1,102 @@
+package models
+
+import "errors"
+
+// Motor command modes
+const (
+	MotorModeVoltage = "voltage"
+	MotorModePWM     = "pwm"
+)
+
+// MotorConfig is the model of the brushed DC motor (with gearbox) driving
+// each wheel. All quantities are reflected to the wheel shaft.
+type MotorConfig struct {
+	Enabled         bool    `json:"enabled"`         // Simulate motors instead of the ideal acceleration clamp
+	SupplyVoltage   float64 `json:"supplyVoltage"`   // Applied voltage saturates at ±supplyVoltage (V)
+	Ke              float64 `json:"ke"`              // Back-EMF constant (V·s/rad)
+	Kt              float64 `json:"kt"`              // Torque constant (N·m/A)
+	Resistance      float64 `json:"resistance"`      // Armature resistance (Ω)
+	Inertia         float64 `json:"inertia"`         // Rotor, gearbox, wheel and share of robot inertia (kg·m²)
+	ViscousFriction float64 `json:"viscousFriction"` // Viscous friction coefficient (N·m·s/rad)
+	MaxCurrent      float64 `json:"maxCurrent"`      // Driver current limit (A), 0 for none
+}
+
+// MotorCommand drives the wheel motors directly when the motor model is
+// enabled, bypassing the wheel velocity command
+type MotorCommand struct {
+	Mode  string  `json:"mode,omitempty"` // "voltage" (default) or "pwm"
+	Left  float64 `json:"left"`           // Volts, or PWM duty cycle in [-1, 1]
+	Right float64 `json:"right"`
+}
+
+// PIDConfig tunes the wheel velocity controller that turns wheelCommand
+// setpoints into motor voltages when the motor model is enabled
+type PIDConfig struct {
+	Enabled       bool    `json:"enabled"`
+	Kp            float64 `json:"kp"`            // Proportional gain (V per rad/s)
+	Ki            float64 `json:"ki"`            // Integral gain (V per rad)
+	Kd            float64 `json:"kd"`            // Derivative gain (V per rad/s²)
+	FeedForward   float64 `json:"feedForward"`   // Velocity feed-forward gain (V per rad/s)
+	IntegralLimit float64 `json:"integralLimit"` // Anti-windup clamp on the integral term (V), 0 for none
+}
+
+// Validate checks that the motor model has physical parameters
+func (c MotorConfig) Validate() error {
+	switch {
+	case !finite(c.SupplyVoltage, c.Ke, c.Kt, c.Resistance, c.Inertia) ||
+		c.SupplyVoltage <= 0 || c.Ke <= 0 || c.Kt <= 0 || c.Resistance <= 0 || c.Inertia <= 0:
+		return errors.New("motor supplyVoltage, ke, kt, resistance and inertia must be positive")
+	case !nonNegative(c.ViscousFriction, c.MaxCurrent):
+		return errors.New("motor viscousFriction and maxCurrent must be at least 0")
+	}
+	return nil
+}
+
+// Validate checks that the wheel velocity loop has no negative gains
+func (c PIDConfig) Validate() error {
+	if !nonNegative(c.Kp, c.Ki, c.Kd, c.FeedForward, c.IntegralLimit) {
+		return errors.New("pid gains and integralLimit must be at least 0")
+	}
+	return nil
+}
+
+// PIDState is one step of a wheel velocity loop
+type PIDState struct {
+	Setpoint float64 `json:"setpoint"` // Commanded wheel velocity (rad/s)
+	Measured float64 `json:"measured"` // Encoder wheel velocity (rad/s)
+	Effort   float64 `json:"effort"`   // Motor voltage after saturation (V)
+}
+
+// WheelPIDState is one step of both wheel velocity loops
+type WheelPIDState struct {
+	Left  PIDState `json:"left"`
+	Right PIDState `json:"right"`
+}
+
+// DefaultMotorConfig returns a 12 V gearmotor that drives the default robot
+// to about 2 m/s. The model is disabled by default.
+func DefaultMotorConfig() MotorConfig {
+	return MotorConfig{
+		Enabled:         false,
+		SupplyVoltage:   12,
+		Ke:              0.25,
+		Kt:              0.25,
+		Resistance:      2,
+		Inertia:         0.01,
+		ViscousFriction: 0.001,
+		MaxCurrent:      3,
+	}
+}
+
+// DefaultPIDConfig returns gains tuned for the default motor. Feed-forward
+// supplies the steady-state voltage; the loop corrects the rest.
+func DefaultPIDConfig() PIDConfig {
+	return PIDConfig{
+		Enabled:       true,
+		Kp:            0.5,
+		Ki:            2,
+		Kd:            0,
+		FeedForward:   0.25,
+		IntegralLimit: 6,
+	}
+}
@@ -0,0 +1,149 @@
+package models
+
+// Feedback sources a controller can close its loop on
+const (
+	FeedbackGroundTruth = "groundTruth"
+	FeedbackOdometry    = "odometry"
+	FeedbackEstimate    = "estimate"
+)
+
+// Navigation events
+const (
+	NavigationProgress        = "progress"
+	NavigationWaypointReached = "waypointReached"
+	NavigationArrived         = "arrived"
+	NavigationFailed          = "failed"
+	NavigationCancelled       = "cancelled"
+)
+
+// Pose is a planar position and heading
+type Pose struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Theta float64 `json:"theta"`
+}
+
+// Waypoint is a navigation goal. Theta, when set, is the heading to turn to
+// on arrival.
+type Waypoint struct {
+	X     float64  `json:"x"`
+	Y     float64  `json:"y"`
+	Theta *float64 `json:"theta,omitempty"`
+}
+
+// NavigationGains are the gains of the polar-coordinate control law
+// (Siegwart et al.): v = kRho ρ, ω = kAlpha α + kBeta β. The law is stable
+// for kRho > 0, kBeta < 0 and kAlpha > kRho.
+type NavigationGains struct {
+	KRho   float64 `json:"kRho"`
+	KAlpha float64 `json:"kAlpha"`
+	KBeta  float64 `json:"kBeta"`
+}
+
+// NavigateToPayload starts the go-to-pose controller on a single target or an
+// ordered list of waypoints
+type NavigateToPayload struct {
+	Target           *Waypoint        `json:"target,omitempty"`
+	Waypoints        []Waypoint       `json:"waypoints,omitempty"`
+	Feedback         string           `json:"feedback,omitempty"`         // "groundTruth", "odometry" (default) or "estimate"
+	Tolerance        float64          `json:"tolerance,omitempty"`        // Arrival distance in meters (default 0.05)
+	HeadingTolerance float64          `json:"headingTolerance,omitempty"` // Arrival heading error in radians (default 0.05)
+	MaxLinearVel     float64          `json:"maxLinearVel,omitempty"`     // Speed cap in m/s (default 0.5)
+	Timeout          float64          `json:"timeout,omitempty"`          // Simulated seconds before giving up (default 120)
+	Gains            *NavigationGains `json:"gains,omitempty"`
+}
+
+// FollowPathPayload starts the pure-pursuit controller on a path
+type FollowPathPayload struct {
+	Points        []Point `json:"points"`                  // Path vertices in order
+	Spline        bool    `json:"spline,omitempty"`        // Follow a Catmull-Rom spline through the points instead of the polyline
+	Feedback      string  `json:"feedback,omitempty"`      // "groundTruth", "odometry" (default) or "estimate"
+	Lookahead     float64 `json:"lookahead,omitempty"`     // Lookahead distance in meters (default 0.3)
+	Speed         float64 `json:"speed,omitempty"`         // Cruise speed in m/s (default 0.3)
+	GoalTolerance float64 `json:"goalTolerance,omitempty"` // Arrival distance from the path end in meters (default 0.05)
+	Timeout       float64 `json:"timeout,omitempty"`       // Simulated seconds before giving up (default 120)
+}
+
+// DWAWeights weight the scores, each scaled to [0, 1], that the dynamic window
+// approach ranks candidate velocities by
+type DWAWeights struct {
+	Heading   float64 `json:"heading"`   // Alignment with the goal at the end of the arc
+	Clearance float64 `json:"clearance"` // Free distance along the arc before a range return
+	Velocity  float64 `json:"velocity"`  // Forward speed
+}
+
+// DWAPayload starts the dynamic window approach local planner toward a goal.
+// It avoids obstacles it sees in the lidar scans, so it needs the lidar.
+type DWAPayload struct {
+	Target         Waypoint    `json:"target"`                   // Goal position; theta is ignored
+	Feedback       string      `json:"feedback,omitempty"`       // "groundTruth", "odometry" (default) or "estimate"
+	Tolerance      float64     `json:"tolerance,omitempty"`      // Arrival distance in meters (default 0.1)
+	MaxLinearVel   float64     `json:"maxLinearVel,omitempty"`   // Speed cap in m/s (default 0.5)
+	MaxAngularVel  float64     `json:"maxAngularVel,omitempty"`  // Turn rate cap in rad/s (default 2)
+	Horizon        float64     `json:"horizon,omitempty"`        // Rollout time of each candidate in seconds (default 1.5)
+	Period         float64     `json:"period,omitempty"`         // Seconds between replans, the width of the window (default 0.1)
+	LinearSamples  int         `json:"linearSamples,omitempty"`  // Linear velocities sampled across the window (default 7)
+	AngularSamples int         `json:"angularSamples,omitempty"` // Angular velocities sampled across the window (default 15)
+	Timeout        float64     `json:"timeout,omitempty"`        // Simulated seconds before giving up (default 120)
+	Weights        *DWAWeights `json:"weights,omitempty"`
+}
+
+// DWAArc is one candidate velocity rolled out over the horizon
+type DWAArc struct {
+	LinearVel  float64 `json:"linearVel"`
+	AngularVel float64 `json:"angularVel"`
+	Clearance  float64 `json:"clearance"`  // Distance the robot can drive along the arc before touching a range return, up to 1 m
+	Admissible bool    `json:"admissible"` // Whether the robot could stop before reaching an obstacle
+	Score      float64 `json:"score"`      // Weighted score; inadmissible arcs score 0
+	Points     []Point `json:"points"`     // Positions along the arc
+}
+
+// DWAArcsPayload is the candidate arcs of one dynamic window replan
+type DWAArcsPayload struct {
+	Chosen     int      `json:"chosen"` // Index of the chosen arc, -1 when none was admissible
+	Candidates []DWAArc `json:"candidates"`
+	SimTime    float64  `json:"simTime"`
+}
+
+// TrackingError is how far the robot is from the path it follows. Errors are
+// measured from the true pose; the feedback cross-track error is what the
+// controller believes.
+type TrackingError struct {
+	CrossTrack         float64 `json:"crossTrack"`         // Signed distance to the path in meters, positive left of it
+	HeadingError       float64 `json:"headingError"`       // Heading minus path direction in radians
+	FeedbackCrossTrack float64 `json:"feedbackCrossTrack"` // Cross-track error of the feedback pose
+}
+
+// NavigationEvent reports the progress of an autonomous controller
+type NavigationEvent struct {
+	Event            string  `json:"event"`      // progress, waypointReached, arrived, failed or cancelled
+	Controller       string  `json:"controller"` // Controller that raised the event
+	Feedback         string  `json:"feedback"`   // Pose source the controller steers by
+	Waypoint         int     `json:"waypoint"`   // Index of the current waypoint
+	Waypoints        int     `json:"waypoints"`  // Number of waypoints
+	Distance         float64 `json:"distance"`   // Distance to the waypoint by the feedback pose
+	HeadingError     float64 `json:"headingError"`
+	GroundTruthError float64 `json:"groundTruthError"` // Distance to the waypoint by the true pose
+	Reason           string  `json:"reason,omitempty"` // Why the controller failed or was cancelled
+	SimTime          float64 `json:"simTime"`
+}
+
+// DefaultDWAWeights returns weights that make for steady progress around
+// obstacles with the default robot
+func DefaultDWAWeights() DWAWeights {
+	return DWAWeights{
+		Heading:   1,
+		Clearance: 2,
+		Velocity:  0.8,
+	}
+}
+
+// DefaultNavigationGains returns gains that converge smoothly for the default
+// robot
+func DefaultNavigationGains() NavigationGains {
+	return NavigationGains{
+		KRho:   0.5,
+		KAlpha: 1.5,
+		KBeta:  -0.3,
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+// Planning algorithms accepted by PlanRequest.Algorithm
+const (
+	PlannerAStar     = "astar"
+	PlannerDijkstra  = "dijkstra"
+	PlannerDStarLite = "dstarlite"
+	PlannerRRT       = "rrt"
+	PlannerRRTStar   = "rrtstar"
+)
+
+// PlanRequest asks for a collision-free path between two poses. The grid
+// planners only use the start and goal positions; the sampling planners also
+// start from the start heading.
+type PlanRequest struct {
+	Start      Pose    `json:"start"`
+	Goal       Pose    `json:"goal"`
+	Algorithm  string  `json:"algorithm,omitempty"`  // "astar" (default), "dijkstra", "dstarlite", "rrt" or "rrtstar"
+	Resolution float64 `json:"resolution,omitempty"` // Grid cell size in meters (default 0.05)
+
+	// Sampling planners only
+	Seed          int64   `json:"seed,omitempty"`          // Sampling seed (0 picks one; the response reports it)
+	Iterations    int     `json:"iterations,omitempty"`    // Samples to draw (default 2000)
+	GoalBias      float64 `json:"goalBias,omitempty"`      // Probability of sampling the goal (default 0.1)
+	GoalTolerance float64 `json:"goalTolerance,omitempty"` // Distance from the goal that counts as reaching it (default 0.1 m)
+	StepTime      float64 `json:"stepTime,omitempty"`      // Duration of each motion primitive in seconds (default 0.5)
+	Speed         float64 `json:"speed,omitempty"`         // Fastest wheel rim speed of the primitives in m/s (default 0.5, capped at MaxSpeed)
+}
+
+// PlanResponse is a planned path and the search effort behind it
+type PlanResponse struct {
+	Algorithm  string  `json:"algorithm"`
+	Found      bool    `json:"found"`
+	Path       []Point `json:"path"`                 // Start to goal, ready for followPath; empty when no path was found
+	Length     float64 `json:"length"`               // Path length in meters
+	Explored   []Point `json:"explored"`             // Expanded cells or tree nodes, in expansion order
+	Resolution float64 `json:"resolution,omitempty"` // Grid cell size in meters
+	Inflation  float64 `json:"inflation"`            // Obstacle inflation radius in meters
+
+	// Sampling planners only
+	Seed       int64          `json:"seed,omitempty"`
+	Iterations int            `json:"iterations,omitempty"` // Samples drawn
+	Cost       float64        `json:"cost,omitempty"`       // Mean wheel rim travel along the path in meters
+	Controls   []TimedControl `json:"controls,omitempty"`   // Wheel commands that drive the path open loop
+	Tree       [][]Point      `json:"tree,omitempty"`       // Every tree edge as a polyline
+}
+
+// TimedControl is a wheel command held for a duration
+type TimedControl struct {
+	WheelCommand
+	Duration float64 `json:"duration"` // Seconds
+}
@@ -1,11 +1,19 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
 
 // WheelState represents the state of a single wheel
 type WheelState struct {
-	Velocity float64 `json:"velocity"` // Angular velocity in rad/s
-	Rotation float64 `json:"rotation"` // Total rotation in radians
+	Velocity float64 `json:"velocity"`          // Angular velocity in rad/s
+	Rotation float64 `json:"rotation"`          // Total rotation in radians
+	Slip     float64 `json:"slip"`              // Fraction of wheel surface speed lost to slip (ground truth only)
+	Voltage  float64 `json:"voltage,omitempty"` // Applied motor voltage, when the motor model is enabled
+	Current  float64 `json:"current,omitempty"` // Motor current in amperes, when the motor model is enabled
 }
 
 // RobotState represents the current state of the robot
@@ -37,7 +45,149 @@ type RobotConstants struct {
 	WheelRadius    float64 `json:"wheelRadius"`    // Wheel radius in meters
 	MaxSpeed       float64 `json:"maxSpeed"`       // Maximum linear speed in m/s
 	MaxAccel       float64 `json:"maxAccel"`       // Maximum acceleration in m/s²
-	SlippageAmount float64 `json:"slippageAmount"` // Slippage noise factor (0-1)
+	SlippageAmount float64 `json:"slippageAmount"` // Wheel slip factor on default terrain (0-1)
+	Seed           int64   `json:"seed,omitempty"` // Noise stream seed (0 keeps the current seed)
+
+	// Optional sections. When updating constants, an omitted section keeps
+	// its current value so older clients do not reset them.
+	Estimator      string                `json:"estimator,omitempty"` // Active pose estimator: "ekf", "pf" or "none"
+	EKF            *EKFConfig            `json:"ekf,omitempty"`
+	ParticleFilter *ParticleFilterConfig `json:"particleFilter,omitempty"`
+	GNSS           *GNSSConfig           `json:"gnss,omitempty"`
+	Lidar          *LidarConfig          `json:"lidar,omitempty"`
+	IMU            *IMUConfig            `json:"imu,omitempty"`
+	Encoders       *EncoderConfig        `json:"encoders,omitempty"`
+	Motor          *MotorConfig          `json:"motor,omitempty"`
+	PID            *PIDConfig            `json:"pid,omitempty"` // Wheel velocity loop, used with the motor model
+}
+
+// Inherit fills the fields of c that were omitted from an update with their
+// values from prev
+func (c *RobotConstants) Inherit(prev RobotConstants) {
+	if c.Seed == 0 {
+		c.Seed = prev.Seed
+	}
+	if c.Estimator == "" {
+		c.Estimator = prev.Estimator
+	}
+	if c.EKF == nil {
+		c.EKF = prev.EKF
+	}
+	if c.ParticleFilter == nil {
+		c.ParticleFilter = prev.ParticleFilter
+	}
+	if c.GNSS == nil {
+		c.GNSS = prev.GNSS
+	}
+	if c.Lidar == nil {
+		c.Lidar = prev.Lidar
+	}
+	if c.IMU == nil {
+		c.IMU = prev.IMU
+	}
+	if c.Encoders == nil {
+		c.Encoders = prev.Encoders
+	}
+	if c.Motor == nil {
+		c.Motor = prev.Motor
+	}
+	if c.PID == nil {
+		c.PID = prev.PID
+	}
+}
+
+// Validate checks the constants of an update. Omitted sections are not
+// checked; they keep values that were validated before.
+func (c RobotConstants) Validate() error {
+	switch {
+	case !finite(c.WheelBase, c.WheelRadius, c.MaxSpeed, c.MaxAccel, c.SlippageAmount):
+		return errors.New("robot constants must be finite numbers")
+	case c.WheelBase <= 0 || c.WheelRadius <= 0:
+		return errors.New("wheelBase and wheelRadius must be positive")
+	case c.MaxSpeed <= 0 || c.MaxAccel <= 0:
+		return errors.New("maxSpeed and maxAccel must be positive")
+	case c.SlippageAmount < 0 || c.SlippageAmount > 1:
+		return errors.New("slippageAmount must be between 0 and 1")
+	}
+	switch c.Estimator {
+	case "", EstimatorEKF, EstimatorParticleFilter, EstimatorNone:
+	default:
+		return fmt.Errorf("unknown estimator %q", c.Estimator)
+	}
+
+	if c.EKF != nil {
+		if err := c.EKF.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.ParticleFilter != nil {
+		if err := c.ParticleFilter.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.GNSS != nil {
+		if err := c.GNSS.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Lidar != nil {
+		if err := c.Lidar.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.IMU != nil {
+		if err := c.IMU.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Encoders != nil {
+		if err := c.Encoders.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Motor != nil {
+		if err := c.Motor.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.PID != nil {
+		if err := c.PID.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finite reports whether every value is a number other than ±Inf
+func finite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonNegative reports whether every value is a finite number of at least 0
+func nonNegative(values ...float64) bool {
+	for _, v := range values {
+		if !(v >= 0) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// RobotSpec describes a robot to add to the simulation
+type RobotSpec struct {
+	ID        string          `json:"id,omitempty"`        // Unique robot ID, generated when empty
+	Constants *RobotConstants `json:"constants,omitempty"` // Defaults when omitted; a zero seed gets a stream of its own
+	Start     Pose            `json:"start"`               // Pose the robot starts from and returns to on reset
+}
+
+// RemoveRobotPayload removes a robot from the simulation
+type RemoveRobotPayload struct {
+	ID string `json:"id"`
 }
 
 // SimulationState contains all simulation data
@@ -52,32 +202,66 @@ type SimulationState struct {
 
 // DefaultRobotConstants returns default robot parameters
 func DefaultRobotConstants() RobotConstants {
+	ekf := DefaultEKFConfig()
+	pf := DefaultParticleFilterConfig()
+	gnss := DefaultGNSSConfig()
+	lidar := DefaultLidarConfig()
+	imu := DefaultIMUConfig()
+	encoders := DefaultEncoderConfig()
+	motor := DefaultMotorConfig()
+	pid := DefaultPIDConfig()
+
 	return RobotConstants{
 		WheelBase:      0.3,  // 30cm between wheels
 		WheelRadius:    0.05, // 5cm wheel radius
 		MaxSpeed:       2.0,  // 2 m/s max
 		MaxAccel:       1.0,  // 1 m/s² acceleration
 		SlippageAmount: 0.1,  // 10% slippage factor
+		Estimator:      EstimatorEKF,
+		EKF:            &ekf,
+		ParticleFilter: &pf,
+		GNSS:           &gnss,
+		Lidar:          &lidar,
+		IMU:            &imu,
+		Encoders:       &encoders,
+		Motor:          &motor,
+		PID:            &pid,
 	}
 }
 
 // Session represents a simulation session in the database
 type Session struct {
-	ID        string         `json:"id"`
-	CreatedAt time.Time      `json:"createdAt"`
-	EndedAt   *time.Time     `json:"endedAt,omitempty"`
-	Constants RobotConstants `json:"constants"`
+	ID             string         `json:"id"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	EndedAt        *time.Time     `json:"endedAt,omitempty"`
+	Constants      RobotConstants `json:"constants"`
+	CommandTimeout float64        `json:"commandTimeout,omitempty"` // Command watchdog timeout in wall-clock seconds, 0 when disabled
+	Events         []SessionEvent `json:"events,omitempty"`
 }
 
-// TrajectoryPoint represents a single point in the robot's trajectory
+// SessionEvent is a notable event logged to a session record
+type SessionEvent struct {
+	Type    string      `json:"type"`
+	RobotID string      `json:"robotId,omitempty"`
+	Time    time.Time   `json:"time"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// TrajectoryPoint represents a single point in the robot's trajectory. A
+// session records the trajectory of its primary robot only.
 type TrajectoryPoint struct {
 	Timestamp     time.Time `json:"timestamp"`
 	TrueX         float64   `json:"trueX"`
 	TrueY         float64   `json:"trueY"`
 	TrueTheta     float64   `json:"trueTheta"`
-	EstX          float64   `json:"estX"`
-	EstY          float64   `json:"estY"`
-	EstTheta      float64   `json:"estTheta"`
+	OdomX         float64   `json:"odomX"`
+	OdomY         float64   `json:"odomY"`
+	OdomTheta     float64   `json:"odomTheta"`
+	LinearVel     float64   `json:"linearVel"`
+	AngularVel    float64   `json:"angularVel"`
 	LeftWheelVel  float64   `json:"leftWheelVel"`
 	RightWheelVel float64   `json:"rightWheelVel"`
+
+	Estimate *PoseEstimate  `json:"estimate,omitempty"` // Filtered estimate, when an estimator is active
+	Tracking *TrackingError `json:"tracking,omitempty"` // Path tracking error, while following a path
 }
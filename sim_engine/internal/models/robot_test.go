@@ -0,0 +1,94 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValidateConstants(t *testing.T) {
+	if err := DefaultRobotConstants().Validate(); err != nil {
+		t.Fatalf("defaults are invalid: %v", err)
+	}
+
+	// An update without optional sections only needs the drivetrain
+	if err := (RobotConstants{WheelBase: 0.3, WheelRadius: 0.05, MaxSpeed: 1, MaxAccel: 1}).Validate(); err != nil {
+		t.Errorf("minimal update is invalid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*RobotConstants)
+	}{
+		{"zero wheel base", func(c *RobotConstants) { c.WheelBase = 0 }},
+		{"negative wheel radius", func(c *RobotConstants) { c.WheelRadius = -1 }},
+		{"zero lidar max range", func(c *RobotConstants) { c.Lidar.MaxRange = 0 }},
+		{"lidar min range at max range", func(c *RobotConstants) { c.Lidar.MinRange = c.Lidar.MaxRange }},
+		{"negative lidar min range", func(c *RobotConstants) { c.Lidar.MinRange = -0.1 }},
+		{"zero lidar fov", func(c *RobotConstants) { c.Lidar.FOV = 0 }},
+		{"lidar fov above a full turn", func(c *RobotConstants) { c.Lidar.FOV = 2*math.Pi + 0.01 }},
+		{"NaN lidar fov", func(c *RobotConstants) { c.Lidar.FOV = math.NaN() }},
+		{"zero lidar updateEvery", func(c *RobotConstants) { c.Lidar.UpdateEvery = 0 }},
+		{"zero lidar beams", func(c *RobotConstants) { c.Lidar.Beams = 0 }},
+		{"negative lidar noise", func(c *RobotConstants) { c.Lidar.NoiseStd = -0.01 }},
+		{"lidar dropout above 1", func(c *RobotConstants) { c.Lidar.DropoutProb = 1.5 }},
+
+		{"NaN wheel base", func(c *RobotConstants) { c.WheelBase = math.NaN() }},
+		{"infinite wheel radius", func(c *RobotConstants) { c.WheelRadius = math.Inf(1) }},
+		{"zero max speed", func(c *RobotConstants) { c.MaxSpeed = 0 }},
+		{"negative max accel", func(c *RobotConstants) { c.MaxAccel = -1 }},
+		{"NaN max accel", func(c *RobotConstants) { c.MaxAccel = math.NaN() }},
+		{"slippage above 1", func(c *RobotConstants) { c.SlippageAmount = 1.5 }},
+		{"unknown estimator", func(c *RobotConstants) { c.Estimator = "ukf" }},
+
+		{"negative EKF noise", func(c *RobotConstants) { c.EKF.LinearVelNoise = -0.1 }},
+		{"NaN EKF noise", func(c *RobotConstants) { c.EKF.AngularVelNoise = math.NaN() }},
+
+		{"zero particles", func(c *RobotConstants) { c.ParticleFilter.NumParticles = 0 }},
+		{"negative particle alpha", func(c *RobotConstants) { c.ParticleFilter.Alpha3 = -0.1 }},
+		{"negative particle spread", func(c *RobotConstants) { c.ParticleFilter.InitialSpread = -1 }},
+		{"particle resample threshold above 1", func(c *RobotConstants) { c.ParticleFilter.ResampleThreshold = 2 }},
+		{"negative particle max beams", func(c *RobotConstants) { c.ParticleFilter.MaxBeams = -1 }},
+
+		{"zero GNSS rate", func(c *RobotConstants) { c.GNSS.Rate = 0 }},
+		{"negative GNSS noise", func(c *RobotConstants) { c.GNSS.NoiseStd = -1 }},
+		{"GNSS outage ending before it starts", func(c *RobotConstants) {
+			c.GNSS.Outages = []OutageWindow{{Start: 5, End: 2}}
+		}},
+
+		{"zero IMU rate", func(c *RobotConstants) { c.IMU.Rate = 0 }},
+		{"negative gyro noise", func(c *RobotConstants) { c.IMU.GyroNoiseStd = -0.01 }},
+		{"negative accel resolution", func(c *RobotConstants) { c.IMU.AccelResolution = -0.001 }},
+		{"NaN gyro bias walk", func(c *RobotConstants) { c.IMU.GyroBiasWalk = math.NaN() }},
+
+		{"zero encoder ticks", func(c *RobotConstants) { c.Encoders.TicksPerRev = 0 }},
+		{"negative encoder jitter", func(c *RobotConstants) { c.Encoders.SampleJitter = -0.001 }},
+		{"missed tick probability above 1", func(c *RobotConstants) { c.Encoders.MissedTickProb = 1.1 }},
+
+		{"zero motor supply voltage", func(c *RobotConstants) { c.Motor.SupplyVoltage = 0 }},
+		{"negative motor resistance", func(c *RobotConstants) { c.Motor.Resistance = -2 }},
+		{"zero motor inertia", func(c *RobotConstants) { c.Motor.Inertia = 0 }},
+		{"negative motor friction", func(c *RobotConstants) { c.Motor.ViscousFriction = -0.001 }},
+		{"negative motor current limit", func(c *RobotConstants) { c.Motor.MaxCurrent = -1 }},
+
+		{"negative PID gain", func(c *RobotConstants) { c.PID.Kp = -0.5 }},
+		{"NaN PID gain", func(c *RobotConstants) { c.PID.Ki = math.NaN() }},
+		{"negative PID integral limit", func(c *RobotConstants) { c.PID.IntegralLimit = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultRobotConstants()
+			tt.modify(&c)
+			if err := c.Validate(); err == nil {
+				t.Error("Validate accepted invalid constants")
+			}
+		})
+	}
+}
+
+func TestValidateLidarFullCircle(t *testing.T) {
+	lidar := DefaultLidarConfig()
+	lidar.FOV = 2 * math.Pi
+	if err := lidar.Validate(); err != nil {
+		t.Errorf("a 360° lidar is invalid: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"errors"
+	"math"
+)
+
+// GNSSConfig configures the simulated absolute position sensor
+type GNSSConfig struct {
+	Enabled  bool           `json:"enabled"`
+	NoiseStd float64        `json:"noiseStd"`          // Std dev of the position error in meters
+	Rate     float64        `json:"rate"`              // Fixes per second
+	Outages  []OutageWindow `json:"outages,omitempty"` // Intervals without fixes
+}
+
+// OutageWindow is an interval of simulated time, in seconds since the last
+// reset, during which a sensor produces no readings
+type OutageWindow struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Contains reports whether simTime falls inside the window
+func (w OutageWindow) Contains(simTime float64) bool {
+	return simTime >= w.Start && simTime < w.End
+}
+
+// Validate checks that the position sensor has a rate and sane outages
+func (c GNSSConfig) Validate() error {
+	switch {
+	case !(c.Rate > 0) || math.IsInf(c.Rate, 0):
+		return errors.New("gnss rate must be positive")
+	case !nonNegative(c.NoiseStd):
+		return errors.New("gnss noiseStd must be at least 0")
+	}
+	for _, outage := range c.Outages {
+		if !finite(outage.Start, outage.End) || outage.End < outage.Start {
+			return errors.New("gnss outages must end after they start")
+		}
+	}
+	return nil
+}
+
+// GNSSReading is a single absolute position fix
+type GNSSReading struct {
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	NoiseStd float64 `json:"noiseStd"` // Std dev the fix was generated with
+	SimTime  float64 `json:"simTime"`  // Simulated time of the fix in seconds
+}
+
+// IMUConfig configures the simulated inertial sensor. Each channel has white
+// noise, a bias that follows a random walk, and an output resolution.
+type IMUConfig struct {
+	Enabled bool    `json:"enabled"`
+	Rate    float64 `json:"rate"` // Readings per second
+
+	GyroNoiseStd   float64 `json:"gyroNoiseStd"`   // Std dev of the yaw rate white noise in rad/s
+	GyroBiasWalk   float64 `json:"gyroBiasWalk"`   // Yaw rate bias random walk in rad/s/√s
+	GyroResolution float64 `json:"gyroResolution"` // Yaw rate quantization step in rad/s (0 disables)
+
+	AccelNoiseStd   float64 `json:"accelNoiseStd"`   // Std dev of the acceleration white noise in m/s²
+	AccelBiasWalk   float64 `json:"accelBiasWalk"`   // Acceleration bias random walk in m/s²/√s
+	AccelResolution float64 `json:"accelResolution"` // Acceleration quantization step in m/s² (0 disables)
+}
+
+// Validate checks that the inertial sensor has a rate and no negative noise
+func (c IMUConfig) Validate() error {
+	switch {
+	case !(c.Rate > 0) || math.IsInf(c.Rate, 0):
+		return errors.New("imu rate must be positive")
+	case !nonNegative(c.GyroNoiseStd, c.GyroBiasWalk, c.GyroResolution,
+		c.AccelNoiseStd, c.AccelBiasWalk, c.AccelResolution):
+		return errors.New("imu noise, bias walk and resolution must be at least 0")
+	}
+	return nil
+}
+
+// IMUReading is a single inertial measurement in the robot frame
+type IMUReading struct {
+	YawRate      float64 `json:"yawRate"`      // Angular velocity about the vertical axis in rad/s
+	ForwardAccel float64 `json:"forwardAccel"` // Acceleration along the heading in m/s²
+	GyroNoiseStd float64 `json:"gyroNoiseStd"` // Std dev the yaw rate was generated with
+	SimTime      float64 `json:"simTime"`      // Simulated time of the reading in seconds
+}
+
+// EncoderConfig configures the simulated wheel encoders
+type EncoderConfig struct {
+	TicksPerRev    int     `json:"ticksPerRev"`    // Counts per wheel revolution
+	SampleJitter   float64 `json:"sampleJitter"`   // Std dev of the counter latch time error in seconds
+	MissedTickProb float64 `json:"missedTickProb"` // Probability that a tick is not counted (0-1)
+}
+
+// Validate checks that the encoders can count
+func (c EncoderConfig) Validate() error {
+	switch {
+	case c.TicksPerRev <= 0:
+		return errors.New("encoders ticksPerRev must be positive")
+	case !nonNegative(c.SampleJitter):
+		return errors.New("encoders sampleJitter must be at least 0")
+	case !(c.MissedTickProb >= 0 && c.MissedTickProb <= 1):
+		return errors.New("encoders missedTickProb must be between 0 and 1")
+	}
+	return nil
+}
+
+// EncoderReading holds the raw tick counters of both wheels. Counters start
+// at 0 on reset and count down when a wheel turns backwards.
+type EncoderReading struct {
+	LeftTicks   int64   `json:"leftTicks"`
+	RightTicks  int64   `json:"rightTicks"`
+	TicksPerRev int     `json:"ticksPerRev"`
+	SimTime     float64 `json:"simTime"` // Simulated time the counters were latched
+}
+
+// LidarConfig configures the simulated planar range sensor
+type LidarConfig struct {
+	Enabled     bool    `json:"enabled"`
+	Beams       int     `json:"beams"`       // Measurements per scan
+	FOV         float64 `json:"fov"`         // Field of view in radians, centered on the heading
+	MinRange    float64 `json:"minRange"`    // Minimum valid range in meters
+	MaxRange    float64 `json:"maxRange"`    // Maximum range in meters
+	NoiseStd    float64 `json:"noiseStd"`    // Std dev of the range error in meters
+	DropoutProb float64 `json:"dropoutProb"` // Probability that a beam returns nothing (0-1)
+	UpdateEvery int     `json:"updateEvery"` // Simulation steps between scans
+}
+
+// Validate checks that the range sensor can produce a scan
+func (c LidarConfig) Validate() error {
+	switch {
+	case c.MaxRange <= 0:
+		return errors.New("lidar maxRange must be positive")
+	case c.MinRange < 0 || c.MinRange >= c.MaxRange:
+		return errors.New("lidar minRange must be at least 0 and below maxRange")
+	case !(c.FOV > 0 && c.FOV <= 2*math.Pi):
+		return errors.New("lidar fov must be in (0, 2π]")
+	case c.UpdateEvery < 1:
+		return errors.New("lidar updateEvery must be at least 1")
+	case c.Beams < 1:
+		return errors.New("lidar beams must be at least 1")
+	case !nonNegative(c.NoiseStd):
+		return errors.New("lidar noiseStd must be at least 0")
+	case !(c.DropoutProb >= 0 && c.DropoutProb <= 1):
+		return errors.New("lidar dropoutProb must be between 0 and 1")
+	}
+	return nil
+}
+
+// LaserScan is a planar range scan, mirroring the ROS sensor_msgs/LaserScan
+// fields. Angles are relative to the robot heading.
+type LaserScan struct {
+	AngleMin       float64   `json:"angleMin"`       // Start angle of the scan in radians
+	AngleMax       float64   `json:"angleMax"`       // End angle of the scan in radians
+	AngleIncrement float64   `json:"angleIncrement"` // Angular distance between measurements in radians
+	TimeIncrement  float64   `json:"timeIncrement"`  // Time between measurements in seconds
+	ScanTime       float64   `json:"scanTime"`       // Time between scans in seconds
+	RangeMin       float64   `json:"rangeMin"`       // Minimum valid range in meters
+	RangeMax       float64   `json:"rangeMax"`       // Maximum valid range in meters
+	Ranges         []float64 `json:"ranges"`         // Measured ranges; dropouts and returns outside [rangeMin, rangeMax) are reported as 0
+	SimTime        float64   `json:"simTime"`        // Simulated time of the scan in seconds
+}
+
+// DefaultLidarConfig returns the default range sensor: a 270° scanner at
+// 10 Hz for the default 120 Hz step rate
+func DefaultLidarConfig() LidarConfig {
+	return LidarConfig{
+		Enabled:     true,
+		Beams:       91,
+		FOV:         1.5 * math.Pi,
+		MinRange:    0.05,
+		MaxRange:    5,
+		NoiseStd:    0.01,
+		DropoutProb: 0.01,
+		UpdateEvery: 12,
+	}
+}
+
+// DefaultEncoderConfig returns the default encoders: 512-line quadrature
+// encoders on the wheel shafts
+func DefaultEncoderConfig() EncoderConfig {
+	return EncoderConfig{
+		TicksPerRev:    2048,
+		SampleJitter:   0.0002,
+		MissedTickProb: 0,
+	}
+}
+
+// DefaultIMUConfig returns the default inertial sensor: a 100 Hz MEMS-grade
+// gyro and accelerometer
+func DefaultIMUConfig() IMUConfig {
+	return IMUConfig{
+		Enabled:         true,
+		Rate:            100,
+		GyroNoiseStd:    0.005,
+		GyroBiasWalk:    0.0005,
+		GyroResolution:  0.0005,
+		AccelNoiseStd:   0.02,
+		AccelBiasWalk:   0.001,
+		AccelResolution: 0.001,
+	}
+}
+
+// DefaultGNSSConfig returns the default absolute position sensor
+func DefaultGNSSConfig() GNSSConfig {
+	return GNSSConfig{
+		Enabled:  true,
+		NoiseStd: 0.3,
+		Rate:     1,
+	}
+}
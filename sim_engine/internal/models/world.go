@@ -0,0 +1,79 @@
+package models
+
+// Obstacle shapes
+const (
+	ObstacleCircle  = "circle"
+	ObstaclePolygon = "polygon"
+)
+
+// Collision responses
+const (
+	CollisionSlide = "slide" // Slide along the contact surface
+	CollisionStop  = "stop"  // Stop at the last collision-free position
+)
+
+// DefaultFriction is the wheel-ground friction coefficient of terrain that no
+// zone covers, roughly rubber on concrete
+const DefaultFriction = 0.8
+
+// Point is a 2D point in meters
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Bounds is an axis-aligned rectangle enclosed by walls
+type Bounds struct {
+	MinX float64 `json:"minX"`
+	MinY float64 `json:"minY"`
+	MaxX float64 `json:"maxX"`
+	MaxY float64 `json:"maxY"`
+}
+
+// Obstacle is a static circle or polygon
+type Obstacle struct {
+	Type   string  `json:"type"`             // "circle" or "polygon"
+	X      float64 `json:"x,omitempty"`      // Circle center X
+	Y      float64 `json:"y,omitempty"`      // Circle center Y
+	Radius float64 `json:"radius,omitempty"` // Circle radius
+	Points []Point `json:"points,omitempty"` // Polygon vertices in order
+}
+
+// TerrainZone is a region of ground with its own friction, such as an ice
+// patch or a carpet. Zones use the obstacle shapes but do not block motion.
+type TerrainZone struct {
+	Name     string  `json:"name,omitempty"`
+	Friction float64 `json:"friction"`         // Wheel-ground friction coefficient
+	Type     string  `json:"type"`             // "circle" or "polygon"
+	X        float64 `json:"x,omitempty"`      // Circle center X
+	Y        float64 `json:"y,omitempty"`      // Circle center Y
+	Radius   float64 `json:"radius,omitempty"` // Circle radius
+	Points   []Point `json:"points,omitempty"` // Polygon vertices in order
+}
+
+// World is a static map of obstacles the robot drives in
+type World struct {
+	Bounds        *Bounds       `json:"bounds,omitempty"` // Boundary walls, nil for an open plane
+	Obstacles     []Obstacle    `json:"obstacles"`
+	CollisionMode string        `json:"collisionMode,omitempty"` // "slide" (default) or "stop"
+	Friction      float64       `json:"friction,omitempty"`      // Friction outside all zones (default 0.8)
+	Terrain       []TerrainZone `json:"terrain,omitempty"`       // Friction zones; later zones cover earlier ones
+}
+
+// CollisionPayload is broadcast when the robot comes into contact with the world
+type CollisionPayload struct {
+	X       float64 `json:"x"` // Robot position at contact
+	Y       float64 `json:"y"`
+	NormalX float64 `json:"normalX"` // Contact normal pointing away from the obstacle
+	NormalY float64 `json:"normalY"`
+	SimTime float64 `json:"simTime"`
+}
+
+// RobotCollisionPayload is broadcast when two robots come into contact
+type RobotCollisionPayload struct {
+	RobotA  string  `json:"robotA"`
+	RobotB  string  `json:"robotB"`
+	X       float64 `json:"x"` // Contact point
+	Y       float64 `json:"y"`
+	SimTime float64 `json:"simTime"`
+}
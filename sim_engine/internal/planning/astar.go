@@ -0,0 +1,58 @@
+package planning
+
+// astar searches from start to goal, expanding cells in order of cost so far
+// plus heuristic. With a zero heuristic it is Dijkstra's algorithm. It returns
+// the path as cell indices, or nil when the goal is unreachable, and the cells
+// it expanded in order.
+func astar(g *grid, start, goal int, heuristic func(a, b int) float64) (path, explored []int) {
+	cost := map[int]float64{start: 0}
+	parent := map[int]int{}
+	closed := map[int]bool{}
+
+	open := newQueue()
+	open.set(start, key{heuristic(start, goal), heuristic(start, goal)})
+
+	for open.Len() > 0 {
+		n, _ := open.pop()
+		closed[n] = true
+		explored = append(explored, n)
+
+		if n == goal {
+			path = []int{goal}
+			for n != start {
+				n = parent[n]
+				path = append(path, n)
+			}
+			reverse(path)
+			return path, explored
+		}
+
+		g.neighbors(n, func(m int, step float64) {
+			if closed[m] {
+				return
+			}
+			c := cost[n] + step
+			if prev, ok := cost[m]; ok && prev <= c {
+				return
+			}
+			cost[m] = c
+			parent[m] = n
+			h := heuristic(m, goal)
+			// Ties go to the cell nearer the goal, which keeps A* from
+			// expanding every equal-cost cell on open ground
+			open.set(m, key{c + h, h})
+		})
+	}
+	return nil, explored
+}
+
+// zero is the heuristic that turns A* into Dijkstra's algorithm
+func zero(a, b int) float64 {
+	return 0
+}
+
+func reverse(cells []int) {
+	for i, j := 0, len(cells)-1; i < j; i, j = i+1, j-1 {
+		cells[i], cells[j] = cells[j], cells[i]
+	}
+}
@@ -0,0 +1,111 @@
+package planning
+
+import "math"
+
+// dstarLite searches backwards from the goal, keeping for every cell g, its
+// cost-to-goal, and rhs, the one-step lookahead of g. A cell is consistent
+// when the two agree. Only the initial search is run here; the same state is
+// what lets D* Lite repair the plan incrementally as the map changes.
+type dstarLite struct {
+	grid        *grid
+	start, goal int
+	g, rhs      []float64
+	open        *queue
+	explored    []int
+}
+
+func newDStarLite(g *grid, start, goal int) *dstarLite {
+	d := &dstarLite{
+		grid:  g,
+		start: start,
+		goal:  goal,
+		g:     make([]float64, len(g.occupied)),
+		rhs:   make([]float64, len(g.occupied)),
+		open:  newQueue(),
+	}
+	for i := range d.g {
+		d.g[i] = math.Inf(1)
+		d.rhs[i] = math.Inf(1)
+	}
+	d.rhs[goal] = 0
+	d.open.set(goal, d.key(goal))
+	return d
+}
+
+// key orders n by its estimated start-to-goal cost through n, breaking ties
+// by its cost-to-goal
+func (d *dstarLite) key(n int) key {
+	m := math.Min(d.g[n], d.rhs[n])
+	return key{m + d.grid.heuristic(d.start, n), m}
+}
+
+// updateVertex recomputes rhs for n and requeues it if it is inconsistent
+func (d *dstarLite) updateVertex(n int) {
+	if n != d.goal {
+		best := math.Inf(1)
+		d.grid.neighbors(n, func(m int, cost float64) {
+			best = math.Min(best, cost+d.g[m])
+		})
+		d.rhs[n] = best
+	}
+	if d.g[n] != d.rhs[n] {
+		d.open.set(n, d.key(n))
+	} else {
+		d.open.remove(n)
+	}
+}
+
+// computeShortestPath expands inconsistent cells until the start is
+// consistent and no queued cell could lower its cost
+func (d *dstarLite) computeShortestPath() {
+	for d.open.Len() > 0 {
+		_, k := d.open.top()
+		if !k.less(d.key(d.start)) && d.rhs[d.start] == d.g[d.start] {
+			return
+		}
+		u, _ := d.open.pop()
+		d.explored = append(d.explored, u)
+
+		if d.g[u] > d.rhs[u] {
+			d.g[u] = d.rhs[u]
+		} else {
+			d.g[u] = math.Inf(1)
+			d.updateVertex(u)
+		}
+		d.grid.neighbors(u, func(m int, _ float64) {
+			d.updateVertex(m)
+		})
+	}
+}
+
+// path follows the steepest descent of g from the start to the goal, or
+// returns nil when the goal is unreachable
+func (d *dstarLite) path() []int {
+	if math.IsInf(d.g[d.start], 1) {
+		return nil
+	}
+
+	path := []int{d.start}
+	for n := d.start; n != d.goal; {
+		next, best := -1, math.Inf(1)
+		d.grid.neighbors(n, func(m int, cost float64) {
+			if c := cost + d.g[m]; c < best {
+				next, best = m, c
+			}
+		})
+		if next < 0 || len(path) > len(d.g) {
+			return nil
+		}
+		path = append(path, next)
+		n = next
+	}
+	return path
+}
+
+// dstar runs D* Lite from start to goal, returning the path and the cells it
+// expanded in order
+func dstar(g *grid, start, goal int) (path, explored []int) {
+	d := newDStarLite(g, start, goal)
+	d.computeShortestPath()
+	return d.path(), d.explored
+}
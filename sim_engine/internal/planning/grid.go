@@ -0,0 +1,145 @@
+package planning
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+// maxCells bounds the grid size so a fine resolution over a large world
+// cannot exhaust memory
+const maxCells = 1 << 20
+
+// openMargin pads the planning area around the start, goal and obstacles of
+// a world without bounds, in meters
+const openMargin = 1.0
+
+// grid is an 8-connected occupancy grid. Cell (i, j) covers
+// [minX+i·res, minX+(i+1)·res) × [minY+j·res, minY+(j+1)·res).
+type grid struct {
+	minX, minY    float64
+	resolution    float64
+	width, height int
+	occupied      []bool
+}
+
+// newGrid rasterizes w at resolution, marking every cell whose center is
+// within radius of an obstacle. A nil world is an empty plane.
+func newGrid(w *world.World, radius, resolution float64, start, goal models.Point) (*grid, error) {
+	minX, minY, maxX, maxY := extent(w, start, goal, radius)
+
+	// Size the grid in float64 so a tiny resolution cannot overflow int
+	// before the check. NaN sizes fail the check too.
+	cols := math.Ceil((maxX - minX) / resolution)
+	rows := math.Ceil((maxY - minY) / resolution)
+	if !(cols*rows <= maxCells) {
+		return nil, fmt.Errorf("grid of %g×%g cells is too large; use a coarser resolution", cols, rows)
+	}
+
+	g := &grid{
+		minX:       minX,
+		minY:       minY,
+		resolution: resolution,
+		width:      int(cols),
+		height:     int(rows),
+	}
+
+	g.occupied = make([]bool, g.width*g.height)
+	if w != nil {
+		for j := 0; j < g.height; j++ {
+			for i := 0; i < g.width; i++ {
+				c := g.center(g.index(i, j))
+				g.occupied[g.index(i, j)] = w.Collides(c.X, c.Y, radius)
+			}
+		}
+	}
+	return g, nil
+}
+
+// extent returns the area to plan over: the world bounds, or the box around
+// the obstacles, start and goal of an open world
+func extent(w *world.World, start, goal models.Point, radius float64) (minX, minY, maxX, maxY float64) {
+	if w != nil && w.Bounds() != nil {
+		b := w.Bounds()
+		return b.MinX, b.MinY, b.MaxX, b.MaxY
+	}
+
+	minX, maxX = math.Min(start.X, goal.X), math.Max(start.X, goal.X)
+	minY, maxY = math.Min(start.Y, goal.Y), math.Max(start.Y, goal.Y)
+	grow := func(x, y, r float64) {
+		minX, maxX = math.Min(minX, x-r), math.Max(maxX, x+r)
+		minY, maxY = math.Min(minY, y-r), math.Max(maxY, y+r)
+	}
+	if w != nil {
+		for _, o := range w.Config().Obstacles {
+			if o.Type == models.ObstacleCircle {
+				grow(o.X, o.Y, o.Radius)
+			}
+			for _, p := range o.Points {
+				grow(p.X, p.Y, 0)
+			}
+		}
+	}
+
+	pad := openMargin + radius
+	return minX - pad, minY - pad, maxX + pad, maxY + pad
+}
+
+// index returns the cell index of (i, j)
+func (g *grid) index(i, j int) int {
+	return j*g.width + i
+}
+
+// cell returns the index of the cell containing p, or false outside the grid
+func (g *grid) cell(p models.Point) (int, bool) {
+	i := int(math.Floor((p.X - g.minX) / g.resolution))
+	j := int(math.Floor((p.Y - g.minY) / g.resolution))
+	if i < 0 || j < 0 || i >= g.width || j >= g.height {
+		return 0, false
+	}
+	return g.index(i, j), true
+}
+
+// center returns the center of cell n
+func (g *grid) center(n int) models.Point {
+	i, j := n%g.width, n/g.width
+	return models.Point{
+		X: g.minX + (float64(i)+0.5)*g.resolution,
+		Y: g.minY + (float64(j)+0.5)*g.resolution,
+	}
+}
+
+// neighbors calls visit for every free cell adjacent to n with the cost of
+// moving there. Diagonal moves must not cut the corner of an occupied cell.
+func (g *grid) neighbors(n int, visit func(m int, cost float64)) {
+	i, j := n%g.width, n/g.width
+	for dj := -1; dj <= 1; dj++ {
+		for di := -1; di <= 1; di++ {
+			if di == 0 && dj == 0 {
+				continue
+			}
+			ni, nj := i+di, j+dj
+			if ni < 0 || nj < 0 || ni >= g.width || nj >= g.height || g.occupied[g.index(ni, nj)] {
+				continue
+			}
+			cost := g.resolution
+			if di != 0 && dj != 0 {
+				if g.occupied[g.index(i+di, j)] || g.occupied[g.index(i, j+dj)] {
+					continue
+				}
+				cost *= math.Sqrt2
+			}
+			visit(g.index(ni, nj), cost)
+		}
+	}
+}
+
+// heuristic returns the octile distance between cells a and b, the exact
+// cost between them on an empty grid
+func (g *grid) heuristic(a, b int) float64 {
+	dx := math.Abs(float64(a%g.width - b%g.width))
+	dy := math.Abs(float64(a/g.width - b/g.width))
+	return g.resolution * (math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy))
+}
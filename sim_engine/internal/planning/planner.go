@@ -0,0 +1,112 @@
+// Package planning finds collision-free paths across a world map
+package planning
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+// DefaultResolution is the grid cell size in meters when a request leaves it
+// unset
+const DefaultResolution = 0.05
+
+// Plan finds a path from req.Start to req.Goal across w for a robot with the
+// given constants. The grid planners search an occupancy grid with obstacles
+// inflated by half the wheel base, so that a path clear of them is clear for
+// the whole robot; the sampling planners grow a tree of drivetrain rollouts
+// checked against the same clearance. A nil world is an empty plane. Failing to find a
+// path is not an error; the response reports it with Found false.
+func Plan(w *world.World, constants models.RobotConstants, req models.PlanRequest) (models.PlanResponse, error) {
+	algorithm := req.Algorithm
+	if algorithm == "" {
+		algorithm = models.PlannerAStar
+	}
+	switch algorithm {
+	case models.PlannerAStar, models.PlannerDijkstra, models.PlannerDStarLite,
+		models.PlannerRRT, models.PlannerRRTStar:
+	default:
+		return models.PlanResponse{}, fmt.Errorf("unknown algorithm %q", req.Algorithm)
+	}
+
+	radius := constants.WheelBase / 2
+	start := models.Point{X: req.Start.X, Y: req.Start.Y}
+	goal := models.Point{X: req.Goal.X, Y: req.Goal.Y}
+	if w != nil && w.Collides(start.X, start.Y, radius) {
+		return models.PlanResponse{}, fmt.Errorf("start is in collision")
+	}
+	if w != nil && w.Collides(goal.X, goal.Y, radius) {
+		return models.PlanResponse{}, fmt.Errorf("goal is in collision")
+	}
+
+	req.Algorithm = algorithm
+	if algorithm == models.PlannerRRT || algorithm == models.PlannerRRTStar {
+		return planRRT(w, constants, req, algorithm == models.PlannerRRTStar)
+	}
+
+	resolution := req.Resolution
+	if resolution == 0 {
+		resolution = DefaultResolution
+	}
+	if !(resolution > 0) || math.IsInf(resolution, 0) {
+		return models.PlanResponse{}, fmt.Errorf("resolution must be positive and finite")
+	}
+
+	g, err := newGrid(w, radius, resolution, start, goal)
+	if err != nil {
+		return models.PlanResponse{}, err
+	}
+	startCell, ok := g.cell(start)
+	if !ok {
+		return models.PlanResponse{}, fmt.Errorf("start is outside the map")
+	}
+	goalCell, ok := g.cell(goal)
+	if !ok {
+		return models.PlanResponse{}, fmt.Errorf("goal is outside the map")
+	}
+	// The start and goal are clear even if their cell centers are not
+	g.occupied[startCell] = false
+	g.occupied[goalCell] = false
+
+	var cells, explored []int
+	switch algorithm {
+	case models.PlannerAStar:
+		cells, explored = astar(g, startCell, goalCell, g.heuristic)
+	case models.PlannerDijkstra:
+		cells, explored = astar(g, startCell, goalCell, zero)
+	case models.PlannerDStarLite:
+		cells, explored = dstar(g, startCell, goalCell)
+	}
+
+	resp := models.PlanResponse{
+		Algorithm:  algorithm,
+		Found:      cells != nil,
+		Path:       []models.Point{},
+		Explored:   make([]models.Point, len(explored)),
+		Resolution: resolution,
+		Inflation:  radius,
+	}
+	for i, n := range explored {
+		resp.Explored[i] = g.center(n)
+	}
+	if cells != nil {
+		resp.Path = simplify(g, cells, start, goal)
+		resp.Length = pathLength(resp.Path)
+	}
+	return resp, nil
+}
+
+// simplify converts a cell path to waypoints, keeping only the cells where
+// the direction changes, and replaces the end cells with the exact start and
+// goal
+func simplify(g *grid, cells []int, start, goal models.Point) []models.Point {
+	points := []models.Point{start}
+	for i := 1; i < len(cells)-1; i++ {
+		if cells[i]-cells[i-1] != cells[i+1]-cells[i] {
+			points = append(points, g.center(cells[i]))
+		}
+	}
+	return append(points, goal)
+}
@@ -0,0 +1,139 @@
+package planning
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+// wallWorld is a 6 m square split by a wall with a gap at the top
+func wallWorld(t *testing.T) *world.World {
+	t.Helper()
+	w, err := world.New(models.World{
+		Bounds: &models.Bounds{MinX: -3, MinY: -3, MaxX: 3, MaxY: 3},
+		Obstacles: []models.Obstacle{{
+			Type:   models.ObstaclePolygon,
+			Points: []models.Point{{X: -0.2, Y: -3}, {X: 0.2, Y: -3}, {X: 0.2, Y: 1.5}, {X: -0.2, Y: 1.5}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("world.New: %v", err)
+	}
+	return w
+}
+
+// checkPath verifies that a path runs from start to goal clear of w
+func checkPath(t *testing.T, w *world.World, radius float64, resp models.PlanResponse, start, goal models.Point) {
+	t.Helper()
+	if !resp.Found || len(resp.Path) < 2 {
+		t.Fatalf("no path found: %+v", resp)
+	}
+	if resp.Path[0] != start || resp.Path[len(resp.Path)-1] != goal {
+		t.Errorf("path runs from %v to %v, want %v to %v", resp.Path[0], resp.Path[len(resp.Path)-1], start, goal)
+	}
+	for i := 1; i < len(resp.Path); i++ {
+		a, b := resp.Path[i-1], resp.Path[i]
+		steps := int(math.Ceil(math.Hypot(b.X-a.X, b.Y-a.Y)/0.01)) + 1
+		for k := 0; k <= steps; k++ {
+			f := float64(k) / float64(steps)
+			x, y := a.X+f*(b.X-a.X), a.Y+f*(b.Y-a.Y)
+			// The grid only guarantees clearance at cell centers
+			if w.Collides(x, y, radius*0.5) {
+				t.Fatalf("segment %d passes through an obstacle at (%g, %g)", i, x, y)
+			}
+		}
+	}
+}
+
+func TestGridPlannersFindPathAroundWall(t *testing.T) {
+	w := wallWorld(t)
+	constants := models.DefaultRobotConstants()
+	start, goal := models.Point{X: -2, Y: -2}, models.Point{X: 2, Y: -2}
+
+	lengths := map[string]float64{}
+	for _, algorithm := range []string{models.PlannerAStar, models.PlannerDijkstra, models.PlannerDStarLite} {
+		t.Run(algorithm, func(t *testing.T) {
+			resp, err := Plan(w, constants, models.PlanRequest{
+				Start:      models.Pose{X: start.X, Y: start.Y},
+				Goal:       models.Pose{X: goal.X, Y: goal.Y},
+				Algorithm:  algorithm,
+				Resolution: 0.1,
+			})
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			checkPath(t, w, constants.WheelBase/2, resp, start, goal)
+			// Through the gap at the top, well beyond the straight line
+			if resp.Length < 8 {
+				t.Errorf("path of %g m cannot have gone around the wall", resp.Length)
+			}
+			lengths[algorithm] = resp.Length
+		})
+	}
+
+	// All three find optimal cell paths; ties between equally short cell
+	// paths and replacing the end cells with the exact start and goal leave
+	// the lengths a few cells apart
+	for algorithm, length := range lengths {
+		if math.Abs(length-lengths[models.PlannerAStar]) > 2*0.1 {
+			t.Errorf("%s path is %g m, A* path is %g m", algorithm, length, lengths[models.PlannerAStar])
+		}
+	}
+}
+
+func TestPlanReportsNoPath(t *testing.T) {
+	w, err := world.New(models.World{
+		Bounds: &models.Bounds{MinX: -3, MinY: -3, MaxX: 3, MaxY: 3},
+		Obstacles: []models.Obstacle{{
+			Type:   models.ObstaclePolygon,
+			Points: []models.Point{{X: -0.2, Y: -3}, {X: 0.2, Y: -3}, {X: 0.2, Y: 3}, {X: -0.2, Y: 3}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := Plan(w, models.DefaultRobotConstants(), models.PlanRequest{
+		Start:      models.Pose{X: -2},
+		Goal:       models.Pose{X: 2},
+		Resolution: 0.1,
+	})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if resp.Found || len(resp.Path) != 0 {
+		t.Errorf("found a path through a closed wall: %v", resp.Path)
+	}
+}
+
+func TestPlanRejectsInvalidResolution(t *testing.T) {
+	w := wallWorld(t)
+	resolutions := []float64{
+		-0.1,
+		math.NaN(),
+		math.Inf(1),
+		6 / math.Pow(2, 32), // 2³² cells a side, whose int product wraps to 0
+		1e-300,
+	}
+	for _, resolution := range resolutions {
+		_, err := Plan(w, models.DefaultRobotConstants(), models.PlanRequest{
+			Start:      models.Pose{X: -2},
+			Goal:       models.Pose{X: 2},
+			Resolution: resolution,
+		})
+		if err == nil {
+			t.Errorf("Plan accepted resolution %g", resolution)
+		}
+	}
+}
+
+func TestPlanRejectsStartInCollision(t *testing.T) {
+	_, err := Plan(wallWorld(t), models.DefaultRobotConstants(), models.PlanRequest{
+		Start: models.Pose{X: 0, Y: 0},
+		Goal:  models.Pose{X: 2},
+	})
+	if err == nil {
+		t.Error("Plan accepted a start inside the wall")
+	}
+}
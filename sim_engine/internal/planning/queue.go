@@ -0,0 +1,74 @@
+package planning
+
+import "container/heap"
+
+// key orders cells in the open list, lexicographically
+type key [2]float64
+
+func (k key) less(o key) bool {
+	return k[0] < o[0] || (k[0] == o[0] && k[1] < o[1])
+}
+
+// queue is a min-priority queue of cells that supports changing and removing
+// queued cells, as D* Lite requires
+type queue struct {
+	cells []int
+	keys  map[int]key
+	pos   map[int]int // Heap position of each queued cell
+}
+
+func newQueue() *queue {
+	return &queue{keys: make(map[int]key), pos: make(map[int]int)}
+}
+
+// set queues n with key k, or updates its key if it is already queued
+func (q *queue) set(n int, k key) {
+	q.keys[n] = k
+	if i, ok := q.pos[n]; ok {
+		heap.Fix(q, i)
+		return
+	}
+	heap.Push(q, n)
+}
+
+// remove drops n from the queue if it is queued
+func (q *queue) remove(n int) {
+	if i, ok := q.pos[n]; ok {
+		heap.Remove(q, i)
+	}
+}
+
+// top returns the cell with the smallest key without removing it
+func (q *queue) top() (int, key) {
+	return q.cells[0], q.keys[q.cells[0]]
+}
+
+// pop removes and returns the cell with the smallest key
+func (q *queue) pop() (int, key) {
+	n := heap.Pop(q).(int)
+	return n, q.keys[n]
+}
+
+// heap.Interface
+
+func (q *queue) Len() int           { return len(q.cells) }
+func (q *queue) Less(i, j int) bool { return q.keys[q.cells[i]].less(q.keys[q.cells[j]]) }
+
+func (q *queue) Swap(i, j int) {
+	q.cells[i], q.cells[j] = q.cells[j], q.cells[i]
+	q.pos[q.cells[i]] = i
+	q.pos[q.cells[j]] = j
+}
+
+func (q *queue) Push(x interface{}) {
+	n := x.(int)
+	q.pos[n] = len(q.cells)
+	q.cells = append(q.cells, n)
+}
+
+func (q *queue) Pop() interface{} {
+	n := q.cells[len(q.cells)-1]
+	q.cells = q.cells[:len(q.cells)-1]
+	delete(q.pos, n)
+	return n
+}
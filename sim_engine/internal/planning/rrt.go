@@ -0,0 +1,498 @@
+package planning
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/simulation"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+// Sampling planner defaults
+const (
+	defaultIterations    = 2000
+	defaultGoalBias      = 0.1
+	defaultGoalTolerance = 0.1
+	defaultStepTime      = 0.5
+	defaultSpeed         = 0.5
+
+	// maxIterations bounds the samples a single request may ask for, since
+	// the planner runs synchronously in the request handler. maxStepTime and
+	// maxSpeed bound the length of each rollout for the same reason.
+	maxIterations = 50000
+	maxStepTime   = 5.0
+	maxSpeed      = 10.0
+)
+
+const (
+	// rolloutDt is the integration step of the primitive rollouts
+	rolloutDt = 1.0 / 60
+	// edgeSamples is the number of trajectory points kept per tree edge
+	edgeSamples = 5
+	// headingWeight converts heading error to meters in the state distance
+	headingWeight = 0.1
+	// steerSlack is how much further from a sample than the nearest node
+	// reached a cheaper parent may land and still be chosen, in meters
+	steerSlack = 0.05
+	// rewireTolerance bounds the position (m) and heading (rad) mismatch at
+	// which a new edge may replace a node's edge
+	rewireTolerance = 0.03
+)
+
+// rrtNode is a tree vertex: a drivetrain state and the primitive that
+// reached it from its parent
+type rrtNode struct {
+	state    simulation.MotionState
+	parent   int // -1 for the root
+	control  models.WheelCommand
+	cost     float64        // Mean wheel rim travel from the root in meters
+	edgeCost float64        // Mean wheel rim travel along the edge from the parent
+	edge     []models.Point // Trajectory from the parent, excluding the parent
+	children []int
+	rollouts []rollout // Every primitive from this node, filled on first use
+}
+
+// rrt grows a kinodynamic tree by rolling out wheel command primitives
+// through the engine's drivetrain model
+type rrt struct {
+	world      *world.World
+	constants  models.RobotConstants
+	radius     float64
+	primitives []models.WheelCommand
+	stepTime   float64
+	nearRadius float64
+	rand       *rand.Rand
+	nodes      []rrtNode
+
+	minX, minY, maxX, maxY float64
+}
+
+// rollout is the outcome of holding a primitive from a state
+type rollout struct {
+	control models.WheelCommand
+	end     simulation.MotionState
+	points  []models.Point // Position after every integration step
+	cost    float64        // Mean wheel rim travel in meters
+	checked bool           // Whether free has been computed
+	free    bool           // Whether the robot stays clear of obstacles
+}
+
+// planRRT runs RRT, or RRT* when star is set. RRT returns the first path to
+// reach the goal. RRT* draws every sample, choosing the cheapest nearby parent
+// for each new node and rewiring nearby nodes through it when it can reach
+// their state more cheaply, and returns the cheapest path found.
+func planRRT(w *world.World, constants models.RobotConstants, req models.PlanRequest, star bool) (models.PlanResponse, error) {
+	iterations := req.Iterations
+	if iterations == 0 {
+		iterations = defaultIterations
+	}
+	goalBias := req.GoalBias
+	if goalBias == 0 {
+		goalBias = defaultGoalBias
+	}
+	tolerance := req.GoalTolerance
+	if tolerance == 0 {
+		tolerance = defaultGoalTolerance
+	}
+	stepTime := req.StepTime
+	if stepTime == 0 {
+		stepTime = defaultStepTime
+	}
+	speed := req.Speed
+	if speed == 0 {
+		speed = defaultSpeed
+	}
+	switch {
+	case iterations < 0:
+		return models.PlanResponse{}, fmt.Errorf("iterations must be positive")
+	case iterations > maxIterations:
+		return models.PlanResponse{}, fmt.Errorf("iterations must be at most %d", maxIterations)
+	case goalBias < 0 || goalBias > 1:
+		return models.PlanResponse{}, fmt.Errorf("goalBias must be between 0 and 1")
+	case tolerance < 0 || stepTime < 0 || speed < 0:
+		return models.PlanResponse{}, fmt.Errorf("goalTolerance, stepTime and speed must be positive")
+	case stepTime > maxStepTime:
+		return models.PlanResponse{}, fmt.Errorf("stepTime must be at most %g s", maxStepTime)
+	case speed > maxSpeed:
+		return models.PlanResponse{}, fmt.Errorf("speed must be at most %g m/s", maxSpeed)
+	}
+	speed = math.Min(speed, constants.MaxSpeed)
+
+	seed := req.Seed
+	for seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	start := models.Point{X: req.Start.X, Y: req.Start.Y}
+	goal := models.Point{X: req.Goal.X, Y: req.Goal.Y}
+	t := &rrt{
+		world:      w,
+		constants:  constants,
+		radius:     constants.WheelBase / 2,
+		primitives: primitives(speed / constants.WheelRadius),
+		stepTime:   stepTime,
+		nearRadius: speed*stepTime + rewireTolerance,
+		rand:       rand.New(rand.NewSource(seed)),
+	}
+	t.minX, t.minY, t.maxX, t.maxY = extent(w, start, goal, t.radius)
+	t.nodes = []rrtNode{{
+		state:  simulation.MotionState{X: start.X, Y: start.Y, Theta: req.Start.Theta},
+		parent: -1,
+	}}
+
+	drawn := 0
+	for drawn < iterations {
+		drawn++
+		target := t.sample(goal, goalBias)
+		n, ok := t.extend(target, star)
+		if !ok {
+			continue
+		}
+		if !star && dist(t.nodes[n].state, goal) <= tolerance {
+			break
+		}
+	}
+
+	resp := models.PlanResponse{
+		Algorithm:  req.Algorithm,
+		Path:       []models.Point{},
+		Explored:   make([]models.Point, len(t.nodes)),
+		Inflation:  t.radius,
+		Seed:       seed,
+		Iterations: drawn,
+	}
+	for i, node := range t.nodes {
+		resp.Explored[i] = models.Point{X: node.state.X, Y: node.state.Y}
+		if node.parent >= 0 {
+			parent := t.nodes[node.parent].state
+			resp.Tree = append(resp.Tree, append([]models.Point{{X: parent.X, Y: parent.Y}}, node.edge...))
+		}
+	}
+
+	best := -1
+	for i, node := range t.nodes {
+		if dist(node.state, goal) <= tolerance && (best < 0 || node.cost < t.nodes[best].cost) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		resp.Found = true
+		resp.Cost = t.nodes[best].cost
+		resp.Path, resp.Controls = t.trace(best)
+		resp.Length = pathLength(resp.Path)
+	}
+	return resp, nil
+}
+
+// primitives returns the wheel commands the tree is grown with: every pair
+// of wheel speeds from full reverse to full forward in half steps that does
+// not drive the robot backwards, so the path suits followPath. Turning in
+// place is included.
+func primitives(wheelSpeed float64) []models.WheelCommand {
+	levels := []float64{-1, -0.5, 0, 0.5, 1}
+	var cmds []models.WheelCommand
+	for _, l := range levels {
+		for _, r := range levels {
+			if l+r < 0 || (l == 0 && r == 0) {
+				continue
+			}
+			cmds = append(cmds, models.WheelCommand{LeftVelocity: l * wheelSpeed, RightVelocity: r * wheelSpeed})
+		}
+	}
+	return cmds
+}
+
+// sample draws a target pose, the goal with probability goalBias and
+// otherwise a uniform pose over the planning area
+func (t *rrt) sample(goal models.Point, goalBias float64) simulation.MotionState {
+	if t.rand.Float64() < goalBias {
+		return simulation.MotionState{X: goal.X, Y: goal.Y, Theta: math.NaN()}
+	}
+	return simulation.MotionState{
+		X:     t.minX + t.rand.Float64()*(t.maxX-t.minX),
+		Y:     t.minY + t.rand.Float64()*(t.maxY-t.minY),
+		Theta: t.rand.Float64() * 2 * math.Pi,
+	}
+}
+
+// extend grows the tree toward target from its nearest node with the
+// primitive that lands closest, returning the new node. With star set, the
+// new node takes the cheapest nearby parent that lands about as close, and
+// nearby nodes are rewired through it.
+func (t *rrt) extend(target simulation.MotionState, star bool) (int, bool) {
+	parent := t.nearest(target)
+	best, ok := t.steer(parent, target)
+	if !ok {
+		return 0, false
+	}
+
+	if star {
+		reach := distance(best.end, target)
+		for _, n := range t.near(best.end) {
+			// Only a node cheaper than the current parent's path can win
+			if n == parent || t.nodes[n].cost >= t.nodes[parent].cost+best.cost {
+				continue
+			}
+			r, ok := t.steer(n, target)
+			if ok && distance(r.end, target) <= reach+steerSlack &&
+				t.nodes[n].cost+r.cost < t.nodes[parent].cost+best.cost {
+				parent, best = n, r
+			}
+		}
+	}
+
+	n := len(t.nodes)
+	t.nodes = append(t.nodes, rrtNode{
+		state:    best.end,
+		parent:   parent,
+		control:  best.control,
+		cost:     t.nodes[parent].cost + best.cost,
+		edgeCost: best.cost,
+		edge:     best.edge(),
+	})
+	t.nodes[parent].children = append(t.nodes[parent].children, n)
+
+	if star {
+		t.rewire(n)
+	}
+	return n, true
+}
+
+// steer returns the collision-free rollout from node n that lands closest to
+// target, or false when every primitive collides
+func (t *rrt) steer(n int, target simulation.MotionState) (rollout, bool) {
+	rollouts := t.rolloutsFrom(n)
+	order := make([]int, len(rollouts))
+	for i := range order {
+		order[i] = i
+	}
+	// Collision checks cost far more than rollouts, so only check the
+	// closest rollouts until one is clear
+	sort.SliceStable(order, func(i, j int) bool {
+		return distance(rollouts[order[i]].end, target) < distance(rollouts[order[j]].end, target)
+	})
+	for _, i := range order {
+		if t.clear(&rollouts[i]) {
+			return rollouts[i], true
+		}
+	}
+	return rollout{}, false
+}
+
+// rolloutsFrom returns the rollouts of every primitive from node n
+func (t *rrt) rolloutsFrom(n int) []rollout {
+	node := &t.nodes[n]
+	if node.rollouts == nil {
+		node.rollouts = make([]rollout, len(t.primitives))
+		for i, cmd := range t.primitives {
+			node.rollouts[i] = t.rollout(node.state, cmd)
+		}
+	}
+	return node.rollouts
+}
+
+// rewire reparents every nearby node that n can reach more cheaply, to
+// within rewireTolerance of its state. The reparented node takes the state
+// the new edge reaches, and its subtree is rolled out again from there; the
+// rewire is abandoned if any edge of the subtree then collides.
+func (t *rrt) rewire(n int) {
+	rollouts := t.rolloutsFrom(n)
+	cost := t.nodes[n].cost
+
+	for _, m := range t.near(t.nodes[n].state) {
+		if m == 0 || m == n || t.nodes[m].cost <= cost || t.isAncestor(m, n) {
+			continue
+		}
+		for i := range rollouts {
+			r := &rollouts[i]
+			if !matches(r.end, t.nodes[m].state, t.constants.WheelRadius) ||
+				cost+r.cost >= t.nodes[m].cost || !t.clear(r) {
+				continue
+			}
+			moved := map[int]rollout{m: *r}
+			if !t.replay(m, r.end, moved) {
+				continue
+			}
+
+			old := &t.nodes[t.nodes[m].parent]
+			for i, c := range old.children {
+				if c == m {
+					old.children = append(old.children[:i], old.children[i+1:]...)
+					break
+				}
+			}
+			t.nodes[m].parent = n
+			t.nodes[n].children = append(t.nodes[n].children, m)
+			for c, r := range moved {
+				t.nodes[c].state = r.end
+				t.nodes[c].control = r.control
+				t.nodes[c].edgeCost = r.cost
+				t.nodes[c].edge = r.edge()
+				t.nodes[c].rollouts = nil
+			}
+			t.updateCosts(m)
+			break
+		}
+	}
+}
+
+// replay rolls out the subtree below node n again from state, recording the
+// new rollouts in moved. It reports false if any of them collides.
+func (t *rrt) replay(n int, state simulation.MotionState, moved map[int]rollout) bool {
+	for _, c := range t.nodes[n].children {
+		r := t.rollout(state, t.nodes[c].control)
+		if !t.clear(&r) {
+			return false
+		}
+		moved[c] = r
+		if !t.replay(c, r.end, moved) {
+			return false
+		}
+	}
+	return true
+}
+
+// updateCosts recomputes the cost of n and its subtree from its parent
+func (t *rrt) updateCosts(n int) {
+	t.nodes[n].cost = t.nodes[t.nodes[n].parent].cost + t.nodes[n].edgeCost
+	for _, c := range t.nodes[n].children {
+		t.updateCosts(c)
+	}
+}
+
+// isAncestor reports whether a is on the path from the root to n
+func (t *rrt) isAncestor(a, n int) bool {
+	for ; n >= 0; n = t.nodes[n].parent {
+		if n == a {
+			return true
+		}
+	}
+	return false
+}
+
+// nearest returns the node closest to target
+func (t *rrt) nearest(target simulation.MotionState) int {
+	best, bestDist := 0, math.Inf(1)
+	for i, node := range t.nodes {
+		if d := distance(node.state, target); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// near returns the nodes within nearRadius of s
+func (t *rrt) near(s simulation.MotionState) []int {
+	var nodes []int
+	for i, node := range t.nodes {
+		if math.Hypot(node.state.X-s.X, node.state.Y-s.Y) <= t.nearRadius {
+			nodes = append(nodes, i)
+		}
+	}
+	return nodes
+}
+
+// rollout holds cmd for one primitive duration from s
+func (t *rrt) rollout(s simulation.MotionState, cmd models.WheelCommand) rollout {
+	steps := max(1, int(math.Round(t.stepTime/rolloutDt)))
+	dt := t.stepTime / float64(steps)
+
+	r := rollout{control: cmd, points: make([]models.Point, steps)}
+	for i := range r.points {
+		s = simulation.PredictMotion(t.constants, s, cmd, dt)
+		r.points[i] = models.Point{X: s.X, Y: s.Y}
+		r.cost += (math.Abs(s.LeftWheelVel) + math.Abs(s.RightWheelVel)) / 2 * t.constants.WheelRadius * dt
+	}
+	r.end = s
+	return r
+}
+
+// clear reports whether the robot stays clear of obstacles along r
+func (t *rrt) clear(r *rollout) bool {
+	if !r.checked {
+		r.checked, r.free = true, true
+		for _, p := range r.points {
+			if t.world != nil && t.world.Collides(p.X, p.Y, t.radius) {
+				r.free = false
+				break
+			}
+		}
+	}
+	return r.free
+}
+
+// trace returns the path and the merged controls from the root to node n
+func (t *rrt) trace(n int) ([]models.Point, []models.TimedControl) {
+	var chain []int
+	for ; n > 0; n = t.nodes[n].parent {
+		chain = append(chain, n)
+	}
+	reverse(chain)
+
+	root := t.nodes[0].state
+	path := []models.Point{{X: root.X, Y: root.Y}}
+	var controls []models.TimedControl
+	for _, c := range chain {
+		for _, p := range t.nodes[c].edge {
+			// Turning in place adds no distance; followPath needs distinct points
+			if last := path[len(path)-1]; math.Hypot(p.X-last.X, p.Y-last.Y) > 1e-3 {
+				path = append(path, p)
+			}
+		}
+		cmd := t.nodes[c].control
+		if k := len(controls) - 1; k >= 0 && controls[k].WheelCommand == cmd {
+			controls[k].Duration += t.stepTime
+		} else {
+			controls = append(controls, models.TimedControl{WheelCommand: cmd, Duration: t.stepTime})
+		}
+	}
+	return path, controls
+}
+
+// edge returns the trajectory points kept for a tree edge: edgeSamples
+// points spread evenly along the rollout, ending at its end
+func (r rollout) edge() []models.Point {
+	n := min(edgeSamples, len(r.points))
+	edge := make([]models.Point, n)
+	for i := range edge {
+		edge[i] = r.points[(i+1)*len(r.points)/n-1]
+	}
+	return edge
+}
+
+// distance is the state-space metric the tree grows by: position error plus
+// weighted heading error. A target with a NaN heading matches any heading.
+func distance(s, target simulation.MotionState) float64 {
+	d := math.Hypot(s.X-target.X, s.Y-target.Y)
+	if !math.IsNaN(target.Theta) {
+		d += headingWeight * math.Abs(math.Remainder(s.Theta-target.Theta, 2*math.Pi))
+	}
+	return d
+}
+
+// dist returns the distance from s to p
+func dist(s simulation.MotionState, p models.Point) float64 {
+	return math.Hypot(s.X-p.X, s.Y-p.Y)
+}
+
+// matches reports whether a is within rewireTolerance of b in position,
+// heading and wheel rim speed
+func matches(a, b simulation.MotionState, wheelRadius float64) bool {
+	return math.Hypot(a.X-b.X, a.Y-b.Y) <= rewireTolerance &&
+		math.Abs(math.Remainder(a.Theta-b.Theta, 2*math.Pi)) <= rewireTolerance &&
+		math.Abs(a.LeftWheelVel-b.LeftWheelVel)*wheelRadius <= rewireTolerance &&
+		math.Abs(a.RightWheelVel-b.RightWheelVel)*wheelRadius <= rewireTolerance
+}
+
+// pathLength returns the length of the polyline through points
+func pathLength(points []models.Point) float64 {
+	length := 0.0
+	for i := 1; i < len(points); i++ {
+		length += math.Hypot(points[i].X-points[i-1].X, points[i].Y-points[i-1].Y)
+	}
+	return length
+}
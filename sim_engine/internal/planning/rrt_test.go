@@ -0,0 +1,97 @@
+package planning
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+func TestSamplingPlannersFindPathAroundWall(t *testing.T) {
+	w := wallWorld(t)
+	constants := models.DefaultRobotConstants()
+	start, goal := models.Point{X: -2, Y: -2}, models.Point{X: 2, Y: -2}
+
+	for _, algorithm := range []string{models.PlannerRRT, models.PlannerRRTStar} {
+		t.Run(algorithm, func(t *testing.T) {
+			resp, err := Plan(w, constants, models.PlanRequest{
+				Start:      models.Pose{X: start.X, Y: start.Y},
+				Goal:       models.Pose{X: goal.X, Y: goal.Y},
+				Algorithm:  algorithm,
+				Iterations: 5000,
+				Seed:       1,
+			})
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			if !resp.Found || len(resp.Path) < 2 {
+				t.Fatalf("no path found in %d iterations", resp.Iterations)
+			}
+			if resp.Path[0] != start {
+				t.Errorf("path starts at %v, want %v", resp.Path[0], start)
+			}
+			end := resp.Path[len(resp.Path)-1]
+			if d := math.Hypot(end.X-goal.X, end.Y-goal.Y); d > defaultGoalTolerance {
+				t.Errorf("path ends %g m from the goal", d)
+			}
+			for i, p := range resp.Path {
+				if w.Collides(p.X, p.Y, constants.WheelBase/2) {
+					t.Fatalf("point %d at (%g, %g) is in collision", i, p.X, p.Y)
+				}
+			}
+			if resp.Length < 8 {
+				t.Errorf("path of %g m cannot have gone around the wall", resp.Length)
+			}
+		})
+	}
+}
+
+func TestSamplingPlannerIsReproducibleWithSeed(t *testing.T) {
+	w := wallWorld(t)
+	req := models.PlanRequest{
+		Start:      models.Pose{X: -2, Y: -2},
+		Goal:       models.Pose{X: 2, Y: -2},
+		Algorithm:  models.PlannerRRT,
+		Iterations: 2000,
+		Seed:       7,
+	}
+	first, err := Plan(w, models.DefaultRobotConstants(), req)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	second, err := Plan(w, models.DefaultRobotConstants(), req)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if first.Seed != req.Seed {
+		t.Errorf("response seed = %d, want %d", first.Seed, req.Seed)
+	}
+	if !reflect.DeepEqual(first.Path, second.Path) {
+		t.Error("the same seed produced different paths")
+	}
+}
+
+func TestSamplingPlannerRejectsInvalidRequests(t *testing.T) {
+	w := wallWorld(t)
+	constants := models.DefaultRobotConstants()
+	constants.MaxSpeed = 100
+	requests := map[string]models.PlanRequest{
+		"negative iterations": {Iterations: -1},
+		"too many iterations": {Iterations: maxIterations + 1},
+		"negative step time":  {StepTime: -0.5},
+		"step time too long":  {StepTime: maxStepTime + 1},
+		"speed too high":      {Speed: maxSpeed + 1},
+		"goal bias above one": {GoalBias: 1.5},
+		"negative speed":      {Speed: -1},
+		"negative tolerance":  {GoalTolerance: -0.1},
+	}
+	for name, req := range requests {
+		req.Start = models.Pose{X: -2, Y: -2}
+		req.Goal = models.Pose{X: 2, Y: -2}
+		req.Algorithm = models.PlannerRRT
+		if _, err := Plan(w, constants, req); err == nil {
+			t.Errorf("%s: Plan accepted %+v", name, req)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// Controller drives the robot autonomously. The engine runs the active
+// controller at the start of every step and drives the twist it returns.
+type Controller interface {
+	// Name identifies the controller in navigation events
+	Name() string
+
+	// Feedback returns the pose source the controller steers by
+	Feedback() string
+
+	// Update returns the twist to drive this step and whether the controller
+	// has finished
+	Update(in ControllerInput) (twist models.TwistCommand, done bool)
+}
+
+// TrackingSource is implemented by controllers that follow a path and can
+// report how far the robot is from it
+type TrackingSource interface {
+	// Tracking returns the tracking error of the last update, or nil
+	Tracking() *models.TrackingError
+}
+
+// ControllerInput carries what a controller may observe in one step
+type ControllerInput struct {
+	Dt        float64
+	SimTime   float64
+	Constants models.RobotConstants
+
+	// Pose from the controller's feedback source
+	Pose models.Pose
+
+	// True pose, used only to report how far off the robot really is
+	GroundTruth models.Pose
+
+	// Velocity measured by wheel odometry
+	Velocity models.TwistCommand
+
+	// Most recent range scan, nil before the first or without the lidar
+	Scan *models.LaserScan
+
+	// Emit raises a navigation event
+	Emit func(models.NavigationEvent)
+
+	// Publish broadcasts any other message, such as visualization data
+	Publish func(msgType string, payload interface{})
+}
+
+// SetController hands the robot to an autonomous controller, cancelling the
+// active one
+func (e *Engine) SetController(c Controller) error {
+	if _, ok := e.feedbackPose(c.Feedback()); !ok {
+		return errFeedbackUnavailable(c.Feedback())
+	}
+	e.CancelController("replaced by " + c.Name())
+	e.controller = c
+	return nil
+}
+
+// CancelController stops the active controller, if any, and the wheels
+func (e *Engine) CancelController(reason string) {
+	if e.controller == nil {
+		return
+	}
+	e.emitNavigation(models.NavigationEvent{
+		Event:      models.NavigationCancelled,
+		Controller: e.controller.Name(),
+		Feedback:   e.controller.Feedback(),
+		Reason:     reason,
+		SimTime:    e.SimTime,
+	})
+	e.stopController()
+}
+
+// ActiveController returns the name of the active controller, or "" under
+// manual control
+func (e *Engine) ActiveController() string {
+	if e.controller == nil {
+		return ""
+	}
+	return e.controller.Name()
+}
+
+// Tracking returns the path tracking error when the active controller
+// follows a path, or nil
+func (e *Engine) Tracking() *models.TrackingError {
+	source, ok := e.controller.(TrackingSource)
+	if !ok {
+		return nil
+	}
+	return source.Tracking()
+}
+
+// stopController drops the active controller and stops the wheels
+func (e *Engine) stopController() {
+	e.controller = nil
+	e.WheelCommand = models.WheelCommand{}
+	e.MotorCommand = nil
+}
+
+// runController asks the active controller for this step's twist
+func (e *Engine) runController(dt float64) {
+	c := e.controller
+	pose, ok := e.feedbackPose(c.Feedback())
+	if !ok {
+		e.emitNavigation(models.NavigationEvent{
+			Event:      models.NavigationFailed,
+			Controller: c.Name(),
+			Feedback:   c.Feedback(),
+			Reason:     errFeedbackUnavailable(c.Feedback()).Error(),
+			SimTime:    e.SimTime,
+		})
+		e.stopController()
+		return
+	}
+
+	twist, done := c.Update(ControllerInput{
+		Dt:          dt,
+		SimTime:     e.SimTime,
+		Constants:   e.Constants,
+		Pose:        pose,
+		GroundTruth: models.Pose{X: e.GroundTruth.X, Y: e.GroundTruth.Y, Theta: e.GroundTruth.Theta},
+		Velocity:    models.TwistCommand{LinearVel: e.Odometry.LinearVel, AngularVel: e.Odometry.AngularVel},
+		Scan:        e.LastScan,
+		Emit:        e.emitNavigation,
+		Publish:     e.emit,
+	})
+	if done {
+		e.stopController()
+		return
+	}
+	e.WheelCommand, _ = twistToWheelCommand(e.Constants, twist)
+	e.MotorCommand = nil
+}
+
+// feedbackPose returns the pose from a feedback source, and false when the
+// source is unknown or unavailable
+func (e *Engine) feedbackPose(source string) (models.Pose, bool) {
+	switch source {
+	case models.FeedbackGroundTruth:
+		return models.Pose{X: e.GroundTruth.X, Y: e.GroundTruth.Y, Theta: e.GroundTruth.Theta}, true
+	case models.FeedbackOdometry:
+		return models.Pose{X: e.Odometry.X, Y: e.Odometry.Y, Theta: e.Odometry.Theta}, true
+	case models.FeedbackEstimate:
+		if e.Estimator == nil {
+			return models.Pose{}, false
+		}
+		estimate := e.Estimator.Estimate()
+		return models.Pose{X: estimate.X, Y: estimate.Y, Theta: estimate.Theta}, true
+	default:
+		return models.Pose{}, false
+	}
+}
+
+func (e *Engine) emitNavigation(event models.NavigationEvent) {
+	e.emit(models.MsgTypeNavigation, event)
+}
+
+// errFeedbackUnavailable is returned when a controller's feedback source
+// cannot provide a pose
+func errFeedbackUnavailable(source string) error {
+	return fmt.Errorf("feedback source %q is unavailable", source)
+}
@@ -0,0 +1,118 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// controllerEngine returns an engine without wheel slip, so controllers
+// steering by ground truth behave the same on every run
+func controllerEngine(t *testing.T) *Engine {
+	t.Helper()
+	e := NewEngineWithSeed(5)
+	c := e.Constants
+	c.SlippageAmount = 0
+	e.UpdateConstants(c)
+	e.Reset()
+	return e
+}
+
+// runUntilDone steps the engine until its controller finishes or the time
+// runs out, and returns the event that ended navigation
+func runUntilDone(t *testing.T, e *Engine, seconds float64) models.NavigationEvent {
+	t.Helper()
+	const dt = 1.0 / 120
+	for step := 0; step < int(seconds/dt); step++ {
+		e.Step(dt)
+		for _, msg := range e.DrainEvents() {
+			if msg.Type == models.MsgTypeCollision {
+				t.Fatalf("collided at %+v", e.GroundTruth)
+			}
+			event, ok := msg.Payload.(models.NavigationEvent)
+			if !ok || msg.Type != models.MsgTypeNavigation {
+				continue
+			}
+			switch event.Event {
+			case models.NavigationArrived, models.NavigationFailed, models.NavigationCancelled:
+				if e.ActiveController() != "" {
+					t.Errorf("controller %s still active after %s", e.ActiveController(), event.Event)
+				}
+				return event
+			}
+		}
+	}
+	t.Fatalf("controller still running after %gs at %+v", seconds, e.GroundTruth)
+	return models.NavigationEvent{}
+}
+
+func TestNavigatorArrivesAtWaypoints(t *testing.T) {
+	e := controllerEngine(t)
+	heading := math.Pi / 2
+	nav, err := NewNavigator(models.NavigateToPayload{
+		Waypoints: []models.Waypoint{
+			{X: 1, Y: 0},
+			{X: 1, Y: 1, Theta: &heading},
+		},
+		Feedback: models.FeedbackGroundTruth,
+	})
+	if err != nil {
+		t.Fatalf("NewNavigator: %v", err)
+	}
+	if err := e.SetController(nav); err != nil {
+		t.Fatalf("SetController: %v", err)
+	}
+
+	event := runUntilDone(t, e, 60)
+	if event.Event != models.NavigationArrived {
+		t.Fatalf("navigation ended with %s: %s", event.Event, event.Reason)
+	}
+	gt := e.GroundTruth
+	if d := math.Hypot(gt.X-1, gt.Y-1); d > 0.1 {
+		t.Errorf("stopped %g m from the goal", d)
+	}
+	if d := math.Abs(angleDiff(gt.Theta, heading)); d > 0.1 {
+		t.Errorf("stopped %g rad off the goal heading", d)
+	}
+}
+
+func TestNavigatorRejectsInvalidRequests(t *testing.T) {
+	target := &models.Waypoint{X: 1}
+	invalid := []models.NavigateToPayload{
+		{},
+		{Target: target, Feedback: "compass"},
+	}
+	for _, req := range invalid {
+		if _, err := NewNavigator(req); err == nil {
+			t.Errorf("NewNavigator accepted %+v", req)
+		}
+	}
+}
+
+func TestManualCommandCancelsController(t *testing.T) {
+	e := controllerEngine(t)
+	nav, err := NewNavigator(models.NavigateToPayload{Target: &models.Waypoint{X: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetController(nav); err != nil {
+		t.Fatal(err)
+	}
+	e.Step(0.01)
+	e.DrainEvents()
+
+	e.SetWheelCommand(models.WheelCommand{LeftVelocity: 1, RightVelocity: 1})
+	if e.ActiveController() != "" {
+		t.Error("a manual command left the controller active")
+	}
+	var cancelled bool
+	for _, msg := range e.DrainEvents() {
+		if event, ok := msg.Payload.(models.NavigationEvent); ok && event.Event == models.NavigationCancelled {
+			cancelled = true
+		}
+	}
+	if !cancelled {
+		t.Error("no cancelled event")
+	}
+}
@@ -0,0 +1,316 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+const (
+	// dwaRolloutStep is the integration step of the candidate rollouts
+	dwaRolloutStep = 0.05
+
+	// dwaPointEvery thins the rollout positions reported per arc
+	dwaPointEvery = 5
+
+	// dwaClearanceCap is how far along an arc obstacles are looked for, in
+	// meters; arcs that are clear that far score the same
+	dwaClearanceCap = 1.0
+
+	// dwaClearanceStep is the distance between obstacle checks along an arc
+	dwaClearanceStep = 0.02
+
+	// dwaMargin is kept between the robot and range returns on top of the
+	// robot radius, absorbing range noise and the gaps between beams
+	dwaMargin = 0.05
+)
+
+// dwa is the dynamic window approach local planner (Fox, Burgard and Thrun).
+// Every period it samples linear/angular velocities reachable within one
+// period at the robot's acceleration limits, rolls each out as a constant
+// arc over the horizon, and drives the admissible arc that best trades goal
+// heading against clearance from the lidar returns and speed.
+type dwa struct {
+	target         models.Point
+	feedback       string
+	tolerance      float64
+	maxLinearVel   float64
+	maxAngularVel  float64
+	horizon        float64
+	period         float64
+	linearSamples  int
+	angularSamples int
+	timeout        float64
+	weights        models.DWAWeights
+
+	obstacles []models.Point // Returns of the latest scan in the feedback frame
+	scanTime  float64        // Simulated time of that scan
+	command   models.TwistCommand
+	nextPlan  float64
+
+	started      float64 // Simulated time of the first update, -1 before it
+	nextProgress float64
+	best         float64 // Smallest distance to the target seen
+	bestTime     float64 // Simulated time best was last improved
+}
+
+// NewDWA creates the dynamic window local planner for a dwa request
+func NewDWA(req models.DWAPayload) (Controller, error) {
+	c := &dwa{
+		target:         models.Point{X: req.Target.X, Y: req.Target.Y},
+		feedback:       req.Feedback,
+		tolerance:      req.Tolerance,
+		maxLinearVel:   req.MaxLinearVel,
+		maxAngularVel:  req.MaxAngularVel,
+		horizon:        req.Horizon,
+		period:         req.Period,
+		linearSamples:  req.LinearSamples,
+		angularSamples: req.AngularSamples,
+		timeout:        req.Timeout,
+		weights:        models.DefaultDWAWeights(),
+		scanTime:       -1,
+		started:        -1,
+	}
+	if c.feedback == "" {
+		c.feedback = models.FeedbackOdometry
+	}
+	if c.tolerance <= 0 {
+		c.tolerance = 0.1
+	}
+	if c.maxLinearVel <= 0 {
+		c.maxLinearVel = 0.5
+	}
+	if c.maxAngularVel <= 0 {
+		c.maxAngularVel = 2
+	}
+	if c.horizon <= 0 {
+		c.horizon = 1.5
+	}
+	if c.period <= 0 {
+		c.period = 0.1
+	}
+	if c.linearSamples <= 0 {
+		c.linearSamples = 7
+	}
+	if c.angularSamples <= 0 {
+		c.angularSamples = 15
+	}
+	if c.timeout <= 0 {
+		c.timeout = 120
+	}
+	if req.Weights != nil {
+		c.weights = *req.Weights
+	}
+
+	switch c.feedback {
+	case models.FeedbackGroundTruth, models.FeedbackOdometry, models.FeedbackEstimate:
+	default:
+		return nil, fmt.Errorf("unknown feedback source %q", c.feedback)
+	}
+	return c, nil
+}
+
+// Name implements Controller
+func (c *dwa) Name() string {
+	return models.MsgTypeDWA
+}
+
+// Feedback implements Controller
+func (c *dwa) Feedback() string {
+	return c.feedback
+}
+
+// Update implements Controller
+func (c *dwa) Update(in ControllerInput) (models.TwistCommand, bool) {
+	if c.started < 0 {
+		c.started = in.SimTime
+		c.best, c.bestTime = math.Inf(1), in.SimTime
+	}
+
+	dist := math.Hypot(c.target.X-in.Pose.X, c.target.Y-in.Pose.Y)
+	event := models.NavigationEvent{
+		Controller:       c.Name(),
+		Feedback:         c.feedback,
+		Waypoints:        1,
+		Distance:         dist,
+		HeadingError:     angleDiff(math.Atan2(c.target.Y-in.Pose.Y, c.target.X-in.Pose.X), in.Pose.Theta),
+		GroundTruthError: math.Hypot(c.target.X-in.GroundTruth.X, c.target.Y-in.GroundTruth.Y),
+		SimTime:          in.SimTime,
+	}
+
+	switch {
+	case dist <= c.tolerance:
+		event.Event = models.NavigationArrived
+	case in.Constants.Lidar == nil || !in.Constants.Lidar.Enabled:
+		event.Event, event.Reason = models.NavigationFailed, "the lidar is disabled"
+	case in.SimTime-c.started > c.timeout:
+		event.Event, event.Reason = models.NavigationFailed, "timeout"
+	case dist < c.best-navStallProgress:
+		c.best, c.bestTime = dist, in.SimTime
+	case in.SimTime-c.bestTime > navStallTime:
+		event.Event, event.Reason = models.NavigationFailed, "stalled"
+	}
+	if event.Event != "" {
+		in.Emit(event)
+		return models.TwistCommand{}, true
+	}
+
+	if in.SimTime >= c.nextProgress {
+		c.nextProgress = in.SimTime + navProgressInterval
+		event.Event = models.NavigationProgress
+		in.Emit(event)
+	}
+
+	if in.Scan != nil && in.Scan.SimTime != c.scanTime {
+		c.scanTime = in.Scan.SimTime
+		c.obstacles = scanPoints(in.Scan, in.Pose)
+	}
+	// Hold still until the first scan shows what is around
+	if c.scanTime < 0 {
+		return models.TwistCommand{}, false
+	}
+
+	if in.SimTime+timeEpsilon >= c.nextPlan {
+		c.nextPlan = in.SimTime + c.period
+		arcs := c.plan(in, dist)
+		c.command = models.TwistCommand{}
+		if arcs.Chosen >= 0 {
+			chosen := arcs.Candidates[arcs.Chosen]
+			c.command = models.TwistCommand{LinearVel: chosen.LinearVel, AngularVel: chosen.AngularVel}
+		}
+		in.Publish(models.MsgTypeDWAArcs, arcs)
+	}
+	return c.command, false
+}
+
+// plan samples the dynamic window around the measured velocity and scores
+// every candidate arc
+func (c *dwa) plan(in ControllerInput, dist float64) models.DWAArcsPayload {
+	constants := in.Constants
+	radius := constants.WheelBase / 2
+
+	// Wheel rim acceleration limits bound how fast v and ω can change
+	linearAccel := constants.MaxAccel
+	angularAccel := 2 * constants.MaxAccel / constants.WheelBase
+
+	// Never plan to pass the target within one period
+	maxV := math.Min(c.maxLinearVel, dist/c.period)
+	v0, w0 := in.Velocity.LinearVel, in.Velocity.AngularVel
+	vLo := math.Max(0, v0-linearAccel*c.period)
+	vHi := math.Max(vLo, math.Min(maxV, v0+linearAccel*c.period))
+	wLo := math.Max(-c.maxAngularVel, w0-angularAccel*c.period)
+	wHi := math.Min(c.maxAngularVel, w0+angularAccel*c.period)
+
+	arcs := models.DWAArcsPayload{Chosen: -1, SimTime: in.SimTime}
+	best := math.Inf(-1)
+	for _, v := range samples(vLo, vHi, c.linearSamples) {
+		for _, w := range samples(wLo, wHi, c.angularSamples) {
+			// Skip velocities the wheels cannot reach
+			if math.Abs(v)+math.Abs(w)*constants.WheelBase/2 > constants.MaxSpeed {
+				continue
+			}
+
+			arc, end := c.rollout(in.Pose, v, w)
+			arc.Clearance = c.freeDistance(in.Pose, v, w, radius)
+			// The robot must be able to brake to a stop before the first
+			// obstacle on the arc, after driving it until the next replan
+			arc.Admissible = v*c.period+v*v/(2*linearAccel) < arc.Clearance || v == 0
+			if arc.Admissible {
+				// Each term is scaled to [0, 1] so the weights are comparable
+				heading := 1 - math.Abs(angleDiff(math.Atan2(c.target.Y-end.Y, c.target.X-end.X), end.Theta))/math.Pi
+				arc.Score = c.weights.Heading*heading +
+					c.weights.Clearance*arc.Clearance/dwaClearanceCap +
+					c.weights.Velocity*v/c.maxLinearVel
+				if arc.Score > best {
+					best = arc.Score
+					arcs.Chosen = len(arcs.Candidates)
+				}
+			}
+			arcs.Candidates = append(arcs.Candidates, arc)
+		}
+	}
+	return arcs
+}
+
+// rollout drives the arc (v, w) from pose over the horizon with the engine's
+// pose integration, returning it and the final pose
+func (c *dwa) rollout(pose models.Pose, v, w float64) (models.DWAArc, models.Pose) {
+	arc := models.DWAArc{
+		LinearVel:  v,
+		AngularVel: w,
+		Points:     []models.Point{{X: pose.X, Y: pose.Y}},
+	}
+
+	steps := max(1, int(math.Round(c.horizon/dwaRolloutStep)))
+	dt := c.horizon / float64(steps)
+	x, y, theta := pose.X, pose.Y, pose.Theta
+	for i := 1; i <= steps; i++ {
+		x, y, theta = advancePose(x, y, theta, v, w, dt)
+		if i%dwaPointEvery == 0 || i == steps {
+			arc.Points = append(arc.Points, models.Point{X: x, Y: y})
+		}
+	}
+	return arc, models.Pose{X: x, Y: y, Theta: theta}
+}
+
+// freeDistance returns how far the robot can drive along the path of the arc
+// (v, w) from pose before it comes within dwaMargin of a range return, up to
+// dwaClearanceCap.
+// The distance depends only on the arc's curvature, not its speed. Turning in
+// place goes nowhere and has none.
+func (c *dwa) freeDistance(pose models.Pose, v, w, radius float64) float64 {
+	if v <= 0 {
+		return 0
+	}
+	// Inside the margin already, only getting closer still is blocked, so the
+	// robot can back off along arcs that lead away
+	limit := math.Min(radius+dwaMargin, c.clearance(pose.X, pose.Y)-1e-3)
+	limit = math.Max(limit, radius)
+
+	curvature := w / v
+	x, y, theta := pose.X, pose.Y, pose.Theta
+	for s := 0.0; s < dwaClearanceCap; s += dwaClearanceStep {
+		if c.clearance(x, y) <= limit {
+			return s
+		}
+		x, y, theta = advancePose(x, y, theta, 1, curvature, dwaClearanceStep)
+	}
+	return dwaClearanceCap
+}
+
+// clearance returns the distance from (x, y) to the nearest range return
+func (c *dwa) clearance(x, y float64) float64 {
+	best := math.Inf(1)
+	for _, p := range c.obstacles {
+		best = math.Min(best, math.Hypot(p.X-x, p.Y-y))
+	}
+	return best
+}
+
+// scanPoints converts the returns of a scan taken at pose to points in the
+// pose's frame. Beams that saw nothing within range are dropped.
+func scanPoints(scan *models.LaserScan, pose models.Pose) []models.Point {
+	var points []models.Point
+	for i, r := range scan.Ranges {
+		if r < scan.RangeMin || r >= scan.RangeMax {
+			continue
+		}
+		angle := pose.Theta + scan.AngleMin + float64(i)*scan.AngleIncrement
+		points = append(points, models.Point{X: pose.X + r*math.Cos(angle), Y: pose.Y + r*math.Sin(angle)})
+	}
+	return points
+}
+
+// samples returns n values spread evenly over [lo, hi], or its midpoint when
+// n is 1
+func samples(lo, hi float64, n int) []float64 {
+	if n <= 1 {
+		return []float64{(lo + hi) / 2}
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = lo + (hi-lo)*float64(i)/float64(n-1)
+	}
+	return values
+}
@@ -0,0 +1,66 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+func TestDWAAvoidsObstacle(t *testing.T) {
+	e := controllerEngine(t)
+	w, err := world.New(models.World{
+		Bounds: &models.Bounds{MinX: -1, MinY: -2, MaxX: 4, MaxY: 2},
+		Obstacles: []models.Obstacle{
+			{Type: models.ObstacleCircle, X: 1.5, Y: 0, Radius: 0.3},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetWorld(w)
+
+	c, err := NewDWA(models.DWAPayload{
+		Target:   models.Waypoint{X: 3, Y: 0},
+		Feedback: models.FeedbackGroundTruth,
+	})
+	if err != nil {
+		t.Fatalf("NewDWA: %v", err)
+	}
+	if err := e.SetController(c); err != nil {
+		t.Fatalf("SetController: %v", err)
+	}
+
+	// runUntilDone fails the test on any collision
+	event := runUntilDone(t, e, 60)
+	if event.Event != models.NavigationArrived {
+		t.Fatalf("DWA ended with %s: %s", event.Event, event.Reason)
+	}
+	if d := math.Hypot(e.GroundTruth.X-3, e.GroundTruth.Y); d > 0.2 {
+		t.Errorf("stopped %g m from the goal", d)
+	}
+}
+
+func TestDWAFailsWithoutLidar(t *testing.T) {
+	e := controllerEngine(t)
+	e.SetWorld(testWorld(t))
+	constants := e.Constants
+	lidar := *constants.Lidar
+	lidar.Enabled = false
+	constants.Lidar = &lidar
+	e.UpdateConstants(constants)
+
+	c, err := NewDWA(models.DWAPayload{Target: models.Waypoint{X: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetController(c); err != nil {
+		t.Fatal(err)
+	}
+
+	event := runUntilDone(t, e, 1)
+	if event.Event != models.NavigationFailed {
+		t.Errorf("DWA without a lidar ended with %s, want failed", event.Event)
+	}
+}
@@ -0,0 +1,199 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// mat3 is a row-major 3x3 matrix
+type mat3 [3][3]float64
+
+// EKF is an Extended Kalman Filter over the pose (x, y, theta). It predicts
+// with wheel odometry, optionally taking the turn rate from the gyro, and
+// corrects with absolute position fixes.
+type EKF struct {
+	config models.EKFConfig
+	mean   [3]float64
+	cov    mat3
+
+	// Latest gyro reading, held until the next one arrives
+	gyro *models.IMUReading
+}
+
+const (
+	// ekfInitialVariance is the pose variance after a reset at a known pose
+	ekfInitialVariance = 1e-6
+
+	// ekfMinVelocityStd keeps the process noise positive while stationary
+	ekfMinVelocityStd = 1e-3
+)
+
+// NewEKF creates an EKF at the origin
+func NewEKF(config models.EKFConfig) *EKF {
+	ekf := &EKF{config: config}
+	ekf.Reset(0, 0, 0)
+	return ekf
+}
+
+// Name implements Estimator
+func (f *EKF) Name() string {
+	return models.EstimatorEKF
+}
+
+// Reset implements Estimator
+func (f *EKF) Reset(x, y, theta float64) {
+	f.mean = [3]float64{x, y, theta}
+	f.cov = mat3{
+		{ekfInitialVariance, 0, 0},
+		{0, ekfInitialVariance, 0},
+		{0, 0, ekfInitialVariance},
+	}
+	f.gyro = nil
+}
+
+// Update implements Estimator
+func (f *EKF) Update(in EstimatorInput) {
+	if in.IMU != nil && f.config.FuseGyro {
+		f.gyro = in.IMU
+	}
+	f.predict(in)
+	if in.GNSS != nil {
+		f.correctPosition(in.GNSS.X, in.GNSS.Y, in.GNSS.NoiseStd)
+	}
+}
+
+// Estimate implements Estimator
+func (f *EKF) Estimate() models.PoseEstimate {
+	estimate := models.PoseEstimate{
+		Estimator: f.Name(),
+		X:         f.mean[0],
+		Y:         f.mean[1],
+		Theta:     f.mean[2],
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			estimate.Covariance[i*3+j] = f.cov[i][j]
+		}
+	}
+	return estimate
+}
+
+// predict propagates the mean through the motion model and the covariance
+// through its Jacobians: Σ = G Σ Gᵀ + V M Vᵀ
+func (f *EKF) predict(in EstimatorInput) {
+	dt := in.Dt
+	v, w := wheelToRobotVelocities(in.Constants, in.LeftWheelVel, in.RightWheelVel)
+	stdW := f.config.AngularVelNoise*math.Abs(w) + ekfMinVelocityStd
+	if f.gyro != nil {
+		// The gyro does not see wheel slip, so its error does not scale
+		// with the encoder turn rate
+		w = f.gyro.YawRate
+		stdW = f.gyro.GyroNoiseStd + ekfMinVelocityStd
+	}
+	x, y, theta := f.mean[0], f.mean[1], f.mean[2]
+
+	// Jacobian of the motion model with respect to the state
+	g := mat3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	if math.Abs(w) < 1e-6 {
+		g[0][2] = -v * dt * math.Sin(theta)
+		g[1][2] = v * dt * math.Cos(theta)
+	} else {
+		radius := v / w
+		g[0][2] = radius * (math.Cos(theta+w*dt) - math.Cos(theta))
+		g[1][2] = radius * (math.Sin(theta+w*dt) - math.Sin(theta))
+	}
+
+	f.mean[0], f.mean[1], f.mean[2] = advancePose(x, y, theta, v, w, dt)
+
+	// Control noise grows with speed. Velocity errors are modeled as white
+	// noise, so the pose variance they add grows linearly with dt; V maps
+	// (v, ω) errors into the state.
+	varV := math.Pow(f.config.LinearVelNoise*math.Abs(v)+ekfMinVelocityStd, 2) * dt
+	varW := stdW * stdW * dt
+	cos, sin := math.Cos(theta), math.Sin(theta)
+	q := mat3{
+		{cos * cos * varV, cos * sin * varV, 0},
+		{cos * sin * varV, sin * sin * varV, 0},
+		{0, 0, varW},
+	}
+
+	f.cov = add3(mul3(mul3(g, f.cov), transpose3(g)), q)
+}
+
+// correctPosition fuses an absolute (x, y) fix: H = [I₂ 0]
+func (f *EKF) correctPosition(zx, zy, std float64) {
+	variance := std * std
+
+	// Innovation covariance S = H Σ Hᵀ + R is the top-left 2x2 block plus R
+	s00 := f.cov[0][0] + variance
+	s01 := f.cov[0][1]
+	s10 := f.cov[1][0]
+	s11 := f.cov[1][1] + variance
+	det := s00*s11 - s01*s10
+	if math.Abs(det) < 1e-12 {
+		return
+	}
+	inv00, inv01 := s11/det, -s01/det
+	inv10, inv11 := -s10/det, s00/det
+
+	// Kalman gain K = Σ Hᵀ S⁻¹ (3x2)
+	var k [3][2]float64
+	for i := 0; i < 3; i++ {
+		k[i][0] = f.cov[i][0]*inv00 + f.cov[i][1]*inv10
+		k[i][1] = f.cov[i][0]*inv01 + f.cov[i][1]*inv11
+	}
+
+	rx := zx - f.mean[0]
+	ry := zy - f.mean[1]
+	for i := 0; i < 3; i++ {
+		f.mean[i] += k[i][0]*rx + k[i][1]*ry
+	}
+	f.mean[2] = normalizeAngle(f.mean[2])
+
+	// Σ = (I - K H) Σ
+	var ikh mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if i == j {
+				ikh[i][j] = 1
+			}
+			if j < 2 {
+				ikh[i][j] -= k[i][j]
+			}
+		}
+	}
+	f.cov = mul3(ikh, f.cov)
+}
+
+func mul3(a, b mat3) mat3 {
+	var out mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				out[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func add3(a, b mat3) mat3 {
+	var out mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			out[i][j] = a[i][j] + b[i][j]
+		}
+	}
+	return out
+}
+
+func transpose3(a mat3) mat3 {
+	var out mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			out[i][j] = a[j][i]
+		}
+	}
+	return out
+}
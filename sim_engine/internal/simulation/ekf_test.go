@@ -0,0 +1,117 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// driveStraight feeds the filter equal wheel speeds for the given time
+func driveStraight(f Estimator, c models.RobotConstants, wheelVel, seconds float64) {
+	const dt = 0.01
+	for t := 0.0; t < seconds-1e-9; t += dt {
+		f.Update(EstimatorInput{
+			Dt:            dt,
+			Constants:     c,
+			LeftWheelVel:  wheelVel,
+			RightWheelVel: wheelVel,
+		})
+	}
+}
+
+func TestEKFPredictsOdometry(t *testing.T) {
+	c := models.DefaultRobotConstants()
+	f := NewEKF(models.DefaultEKFConfig())
+
+	driveStraight(f, c, 10, 1)
+
+	estimate := f.Estimate()
+	want := 10 * c.WheelRadius
+	if math.Abs(estimate.X-want) > 1e-9 || math.Abs(estimate.Y) > 1e-9 || math.Abs(estimate.Theta) > 1e-9 {
+		t.Errorf("estimate = (%g, %g, %g), want (%g, 0, 0)", estimate.X, estimate.Y, estimate.Theta, want)
+	}
+	if estimate.Covariance[0] <= ekfInitialVariance {
+		t.Errorf("x variance %g did not grow while driving", estimate.Covariance[0])
+	}
+}
+
+func TestEKFFusesPositionFix(t *testing.T) {
+	c := models.DefaultRobotConstants()
+	f := NewEKF(models.DefaultEKFConfig())
+	driveStraight(f, c, 10, 2)
+
+	before := f.Estimate()
+	f.Update(EstimatorInput{
+		Dt:        0.01,
+		Constants: c,
+		GNSS:      &models.GNSSReading{X: before.X + 0.5, Y: 0.3, NoiseStd: 0.05},
+	})
+	after := f.Estimate()
+
+	if after.X <= before.X || after.Y <= 0 {
+		t.Errorf("fix did not pull the estimate towards it: (%g, %g) -> (%g, %g)", before.X, before.Y, after.X, after.Y)
+	}
+	if after.Covariance[0] >= before.Covariance[0] || after.Covariance[4] >= before.Covariance[4] {
+		t.Errorf("fix did not shrink the position variance")
+	}
+}
+
+func TestEKFFusesGyro(t *testing.T) {
+	c := models.DefaultRobotConstants()
+	config := models.DefaultEKFConfig()
+	config.FuseGyro = true
+	f := NewEKF(config)
+
+	// The wheels report a straight line but the gyro sees a turn
+	f.Update(EstimatorInput{
+		Dt:            0.1,
+		Constants:     c,
+		LeftWheelVel:  10,
+		RightWheelVel: 10,
+		IMU:           &models.IMUReading{YawRate: 0.5, GyroNoiseStd: 0.01},
+	})
+	if theta := f.Estimate().Theta; math.Abs(theta-0.05) > 1e-9 {
+		t.Errorf("theta = %g, want the gyro turn of 0.05", theta)
+	}
+}
+
+func TestUpdateConstantsKeepsEstimatorForSameConfig(t *testing.T) {
+	e := NewEngineWithSeed(1)
+	estimator := e.Estimator
+
+	// An update decodes into fresh pointers holding the same values
+	same := *e.Constants.EKF
+	e.UpdateConstants(models.RobotConstants{
+		WheelBase:   e.Constants.WheelBase,
+		WheelRadius: e.Constants.WheelRadius,
+		EKF:         &same,
+	})
+	if e.Estimator != estimator {
+		t.Error("an identical EKF config restarted the estimator")
+	}
+
+	changed := same
+	changed.LinearVelNoise *= 2
+	e.UpdateConstants(models.RobotConstants{
+		WheelBase:   e.Constants.WheelBase,
+		WheelRadius: e.Constants.WheelRadius,
+		EKF:         &changed,
+	})
+	if e.Estimator == estimator {
+		t.Error("a changed EKF config did not restart the estimator")
+	}
+}
+
+func TestSameConfig(t *testing.T) {
+	a, b := models.DefaultEKFConfig(), models.DefaultEKFConfig()
+	if !sameConfig(&a, &b) {
+		t.Error("equal configs differ")
+	}
+	if sameConfig(&a, nil) || sameConfig(nil, &b) {
+		t.Error("a config equals nil")
+	}
+	if !sameConfig[models.EKFConfig](nil, nil) {
+		t.Error("nil differs from nil")
+	}
+}
@@ -1,32 +1,106 @@
 package simulation
 
 import (
-	"log"
 	"math"
 	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
 )
 
+// simEpoch is the reference instant that simulation time is measured from.
+// Timestamps are derived from accumulated dt so that runs are reproducible.
+var simEpoch = time.Unix(0, 0).UTC()
+
 // Engine handles the robot simulation logic
 type Engine struct {
-	GroundTruth  models.RobotState
-	Odometry     models.OdometryEstimate
-	Constants    models.RobotConstants
-	LastUpdate   time.Time
-	Running      bool
-	WheelCommand models.WheelCommand
-	rand         *rand.Rand
+	GroundTruth   models.RobotState
+	Odometry      models.OdometryEstimate
+	Constants     models.RobotConstants
+	LastUpdate    time.Time
+	SimTime       float64 // Seconds of simulated time since the last reset
+	Running       bool
+	WheelCommand  models.WheelCommand
+	MotorCommand  *models.MotorCommand // Direct motor drive, nil when the wheels follow WheelCommand
+	Estimator     Estimator            // Filtered pose estimator, nil when disabled
+	World         *world.World         // Static obstacles, nil for an empty plane
+	rand          *rand.Rand
+	gnss          gnssSensor
+	lidar         lidarSensor
+	imu           imuSensor
+	encoders      encoderSensor
+	estimatorRand *rand.Rand
+	LastScan      *models.LaserScan // Most recent range scan, nil before the first
+	StartPose     models.Pose       // Pose the robot is placed at on reset
+
+	wheelAccel  [2]float64 // Angular acceleration of the left and right wheel this step (rad/s²)
+	groundSpeed [2]float64 // Speed at which each wheel moves the robot over the ground (m/s)
+	pid         [2]pidController
+	pidState    *models.WheelPIDState // Wheel velocity loops of the last step, nil when they did not run
+
+	controller Controller // Autonomous controller, nil under manual control
+	watchdog   watchdog
+
+	inContact bool               // Whether ground truth touched the world last step
+	events    []models.WSMessage // Events raised since the last DrainEvents
+}
+
+const (
+	// gravity converts a friction coefficient into the ground acceleration
+	// a wheel can transmit before it spins or skids
+	gravity = 9.81
+
+	// slipScale is the creep slip at SlippageAmount 1 on default terrain
+	slipScale = 0.3
+
+	// slipNoise is the relative std dev of the creep slip
+	slipNoise = 0.3
+)
+
+// Independent noise streams derived from the seed. Each subsystem draws from
+// its own stream so enabling a sensor does not perturb the ground truth.
+const (
+	streamSlippage = iota
+	streamGNSS
+	streamEstimator
+	streamLidar
+	streamIMU
+	streamEncoders
+)
+
+// streamSeed derives the seed of the noise stream with the given ID
+func streamSeed(seed int64, stream int64) int64 {
+	return seed ^ (stream * 0x5851F42D4C957F2D)
+}
+
+// reseed restarts every noise stream from the current seed. Streams are
+// reseeded in place because subsystems hold on to them.
+func (e *Engine) reseed() {
+	e.rand.Seed(streamSeed(e.Constants.Seed, streamSlippage))
+	e.gnss.rand.Seed(streamSeed(e.Constants.Seed, streamGNSS))
+	e.estimatorRand.Seed(streamSeed(e.Constants.Seed, streamEstimator))
+	e.lidar.rand.Seed(streamSeed(e.Constants.Seed, streamLidar))
+	e.imu.rand.Seed(streamSeed(e.Constants.Seed, streamIMU))
+	e.encoders.rand.Seed(streamSeed(e.Constants.Seed, streamEncoders))
 }
 
-// NewEngine creates a new simulation engine
+// NewEngine creates a new simulation engine. The noise stream is seeded from
+// the clock; use NewEngineWithSeed for reproducible runs.
 func NewEngine() *Engine {
+	return NewEngineWithSeed(0)
+}
+
+// NewEngineWithSeed creates a new simulation engine whose noise stream is
+// seeded with seed. A seed of 0 picks a random seed, which is recorded in
+// Constants.Seed so the run can be reproduced later.
+func NewEngineWithSeed(seed int64) *Engine {
 	constants := models.DefaultRobotConstants()
-	now := time.Now()
+	constants.Seed = resolveSeed(seed)
+	now := simEpoch
 
-	return &Engine{
+	e := &Engine{
 		GroundTruth: models.RobotState{
 			X:          0,
 			Y:          0,
@@ -46,31 +120,123 @@ func NewEngine() *Engine {
 			LeftWheel:  models.WheelState{Velocity: 0, Rotation: 0},
 			RightWheel: models.WheelState{Velocity: 0, Rotation: 0},
 		},
-		Constants:    constants,
-		LastUpdate:   now,
-		Running:      false,
-		WheelCommand: models.WheelCommand{LeftVelocity: 0, RightVelocity: 0},
-		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		Constants:     constants,
+		LastUpdate:    now,
+		SimTime:       0,
+		Running:       false,
+		WheelCommand:  models.WheelCommand{LeftVelocity: 0, RightVelocity: 0},
+		rand:          rand.New(rand.NewSource(0)),
+		gnss:          gnssSensor{rand: rand.New(rand.NewSource(0))},
+		lidar:         lidarSensor{rand: rand.New(rand.NewSource(0))},
+		imu:           imuSensor{rand: rand.New(rand.NewSource(0))},
+		encoders:      encoderSensor{rand: rand.New(rand.NewSource(0))},
+		estimatorRand: rand.New(rand.NewSource(0)),
+	}
+	e.reseed()
+	e.Estimator = newEstimator(constants, e.estimatorRand)
+	return e
+}
+
+// resolveSeed returns seed, or a clock-derived seed when seed is 0
+func resolveSeed(seed int64) int64 {
+	for seed == 0 {
+		seed = time.Now().UnixNano()
 	}
+	return seed
 }
 
-// SetWheelCommand updates the target wheel velocities
+// SetWheelCommand updates the target wheel velocities and ends any direct
+// motor command. A manual command cancels the active controller.
 func (e *Engine) SetWheelCommand(cmd models.WheelCommand) {
+	e.CancelController("manual command")
 	e.WheelCommand = cmd
+	e.MotorCommand = nil
+	e.feedWatchdog()
 }
 
-// UpdateConstants updates the robot's physical parameters
+// SetTwistCommand converts a robot velocity command into wheel velocities
+// and applies them
+func (e *Engine) SetTwistCommand(twist models.TwistCommand) models.TwistResult {
+	cmd, applied := twistToWheelCommand(e.Constants, twist)
+	e.SetWheelCommand(cmd)
+
+	return models.TwistResult{
+		Requested:    twist,
+		Applied:      applied,
+		Limited:      applied != twist,
+		WheelCommand: cmd,
+	}
+}
+
+// UpdateConstants updates the robot's physical parameters. Omitted sections
+// and a zero seed keep their current values; a new non-zero seed reseeds the
+// noise streams.
 func (e *Engine) UpdateConstants(constants models.RobotConstants) {
+	prev := e.Constants
+	constants.Inherit(prev)
 	e.Constants = constants
+
+	if constants.Seed != prev.Seed {
+		e.reseed()
+	}
+	if constants.Estimator != prev.Estimator || !sameConfig(constants.EKF, prev.EKF) ||
+		!sameConfig(constants.ParticleFilter, prev.ParticleFilter) {
+		e.restartEstimator()
+	}
+}
+
+// sameConfig reports whether two optional config sections hold the same
+// values. Updates decode into fresh pointers, so comparing the pointers
+// would treat every update as a change.
+func sameConfig[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// restartEstimator replaces the estimator with the one selected by the
+// constants, starting from the best pose currently known
+func (e *Engine) restartEstimator() {
+	x, y, theta := e.Odometry.X, e.Odometry.Y, e.Odometry.Theta
+	if e.Estimator != nil {
+		estimate := e.Estimator.Estimate()
+		x, y, theta = estimate.X, estimate.Y, estimate.Theta
+	}
+
+	e.Estimator = newEstimator(e.Constants, e.estimatorRand)
+	if e.Estimator != nil {
+		e.Estimator.Reset(x, y, theta)
+	}
+}
+
+// SetWorld replaces the static obstacle map. A nil world is an empty plane.
+func (e *Engine) SetWorld(w *world.World) {
+	e.World = w
+	e.inContact = false
+	e.LastScan = nil
+}
+
+// SetSeed reseeds the noise streams. A seed of 0 picks a random seed.
+func (e *Engine) SetSeed(seed int64) {
+	e.Constants.Seed = resolveSeed(seed)
+	e.reseed()
+}
+
+// Seed returns the seed of the current noise stream
+func (e *Engine) Seed() int64 {
+	return e.Constants.Seed
 }
 
-// Reset resets the simulation to initial state
+// Reset resets the simulation to initial state. The noise stream restarts
+// from the current seed, so a reset followed by the same command stream
+// reproduces the same trajectory.
 func (e *Engine) Reset() {
-	now := time.Now()
+	now := simEpoch
 	e.GroundTruth = models.RobotState{
-		X:          0,
-		Y:          0,
-		Theta:      0,
+		X:          e.StartPose.X,
+		Y:          e.StartPose.Y,
+		Theta:      e.StartPose.Theta,
 		LinearVel:  0,
 		AngularVel: 0,
 		LeftWheel:  models.WheelState{Velocity: 0, Rotation: 0},
@@ -78,38 +244,65 @@ func (e *Engine) Reset() {
 		Timestamp:  now,
 	}
 	e.Odometry = models.OdometryEstimate{
-		X:          0,
-		Y:          0,
-		Theta:      0,
+		X:          e.StartPose.X,
+		Y:          e.StartPose.Y,
+		Theta:      e.StartPose.Theta,
 		LinearVel:  0,
 		AngularVel: 0,
 		LeftWheel:  models.WheelState{Velocity: 0, Rotation: 0},
 		RightWheel: models.WheelState{Velocity: 0, Rotation: 0},
 	}
 	e.LastUpdate = now
+	e.SimTime = 0
 	e.WheelCommand = models.WheelCommand{LeftVelocity: 0, RightVelocity: 0}
+	e.MotorCommand = nil
+	e.controller = nil
+	e.watchdog = watchdog{timeout: e.watchdog.timeout}
+	e.gnss.nextSample = 0
+	e.lidar.steps = 0
+	e.imu = imuSensor{rand: e.imu.rand}
+	e.encoders = encoderSensor{rand: e.encoders.rand}
+	e.wheelAccel = [2]float64{}
+	e.groundSpeed = [2]float64{}
+	e.resetPID()
+	e.LastScan = nil
+	e.inContact = false
+	e.events = nil
+	e.reseed()
+	if e.Estimator != nil {
+		e.Estimator.Reset(e.StartPose.X, e.StartPose.Y, e.StartPose.Theta)
+	}
 }
 
 // Step advances the simulation by one time step
 func (e *Engine) Step(dt float64) {
-	startTime := time.Now()
-	defer func() {
-		elapsed := time.Since(startTime)
-		log.Printf("Engine.Step() took %v", elapsed)
-	}()
-
 	if dt <= 0 {
 		return
 	}
 
-	// Update wheel velocities toward commanded velocities with acceleration limits
-	e.updateWheelVelocities(dt)
+	// Stop the wheels if manual commands stopped arriving, then let the
+	// active controller pick this step's command
+	e.checkWatchdog()
+	if e.controller != nil {
+		e.runController(dt)
+	}
 
-	// Calculate robot velocities from wheel velocities
-	linearVel, angularVel := e.wheelVelocitiesToRobotVelocities(
-		e.GroundTruth.LeftWheel.Velocity,
-		e.GroundTruth.RightWheel.Velocity,
-	)
+	// Update wheel velocities toward commanded velocities, through the motor
+	// dynamics when they are simulated
+	prevLeft, prevRight := e.GroundTruth.LeftWheel.Velocity, e.GroundTruth.RightWheel.Velocity
+	if m := e.motorConfig(); m != nil {
+		e.updateMotors(*m, dt)
+	} else {
+		e.resetPID()
+		e.updateWheelVelocities(dt)
+	}
+	e.wheelAccel[0] = (e.GroundTruth.LeftWheel.Velocity - prevLeft) / dt
+	e.wheelAccel[1] = (e.GroundTruth.RightWheel.Velocity - prevRight) / dt
+
+	// Wheels spin at their commanded rate whether or not the robot slips
+	e.GroundTruth.LeftWheel.Rotation += e.GroundTruth.LeftWheel.Velocity * dt
+	e.GroundTruth.RightWheel.Rotation += e.GroundTruth.RightWheel.Velocity * dt
+	left, right := e.GroundTruth.LeftWheel, e.GroundTruth.RightWheel
 
 	// Run ground truth and odometry updates concurrently
 	var wg sync.WaitGroup
@@ -118,34 +311,107 @@ func (e *Engine) Step(dt float64) {
 	// Ground truth update (with slippage)
 	go func() {
 		defer wg.Done()
-		e.updateGroundTruth(linearVel, angularVel, dt)
+		e.updateGroundTruth(dt)
 	}()
 
-	// Odometry update (no slippage)
+	// Odometry update (from encoder ticks, no slippage)
 	go func() {
 		defer wg.Done()
-		e.updateOdometry(dt)
+		e.updateOdometry(left, right, dt, e.SimTime+dt)
 	}()
 
 	wg.Wait()
 
-	e.GroundTruth.Timestamp = time.Now()
+	e.SimTime += dt
+	e.GroundTruth.Timestamp = simTimestamp(e.SimTime)
 	e.LastUpdate = e.GroundTruth.Timestamp
+
+	// Sample sensors and run the estimator on this step's data
+	fix := e.gnss.sample(e.Constants.GNSS, e.GroundTruth, e.SimTime)
+	if fix != nil {
+		e.emit(models.MsgTypeGNSS, fix)
+	}
+	scan := e.lidar.sample(e.Constants.Lidar, e.rangeMap(), e.GroundTruth, e.SimTime, dt)
+	if scan != nil {
+		e.LastScan = scan
+		e.emit(models.MsgTypeLaserScan, scan)
+	}
+	imu := e.imu.sample(e.Constants.IMU, e.GroundTruth, e.SimTime, dt)
+	if imu != nil {
+		e.emit(models.MsgTypeIMU, imu)
+	}
+	if e.Estimator != nil {
+		e.Estimator.Update(EstimatorInput{
+			Dt:            dt,
+			Constants:     e.Constants,
+			LeftWheelVel:  e.Odometry.LeftWheel.Velocity,
+			RightWheelVel: e.Odometry.RightWheel.Velocity,
+			GNSS:          fix,
+			IMU:           imu,
+			Scan:          scan,
+			Map:           e.rangeMap(),
+		})
+	}
 }
 
 // updateGroundTruth updates the ground truth state with slippage
-func (e *Engine) updateGroundTruth(linearVel, angularVel, dt float64) {
-	// Apply slippage to velocities (ground truth only)
-	slippedLinearVel, slippedAngularVel := e.applySlippage(linearVel, angularVel, dt)
+func (e *Engine) updateGroundTruth(dt float64) {
+	// Robot velocities from what the wheels transmit to the ground
+	slippedLinearVel, slippedAngularVel := e.applySlippage(dt)
 
 	// Update ground truth position with slippage
+	prevX, prevY := e.GroundTruth.X, e.GroundTruth.Y
 	e.GroundTruth.LinearVel = slippedLinearVel
 	e.GroundTruth.AngularVel = slippedAngularVel
 	e.updatePosition(&e.GroundTruth, slippedLinearVel, slippedAngularVel, dt)
 
-	// Update wheel rotations based on actual wheel velocities
-	e.GroundTruth.LeftWheel.Rotation += e.GroundTruth.LeftWheel.Velocity * dt
-	e.GroundTruth.RightWheel.Rotation += e.GroundTruth.RightWheel.Velocity * dt
+	// Keep the robot out of obstacles
+	if e.World != nil {
+		e.resolveCollisions(prevX, prevY, dt)
+	}
+}
+
+// resolveCollisions moves ground truth out of any obstacle it entered this
+// step, either sliding along the contact surface or stopping in place, and
+// raises a collision event when contact begins
+func (e *Engine) resolveCollisions(prevX, prevY, dt float64) {
+	gt := &e.GroundTruth
+	radius := e.Constants.WheelBase / 2
+
+	x, y, hit, nx, ny := e.World.Resolve(gt.X, gt.Y, radius)
+	if hit && e.World.CollisionMode() == models.CollisionStop && !e.World.Collides(prevX, prevY, radius) {
+		x, y = prevX, prevY
+	}
+
+	if hit {
+		// Only the motion that survived the contact counts as velocity
+		gt.LinearVel = ((x-prevX)*math.Cos(gt.Theta) + (y-prevY)*math.Sin(gt.Theta)) / dt
+		gt.X, gt.Y = x, y
+
+		if !e.inContact {
+			e.emit(models.MsgTypeCollision, models.CollisionPayload{
+				X:       x,
+				Y:       y,
+				NormalX: nx,
+				NormalY: ny,
+				SimTime: e.SimTime + dt,
+			})
+		}
+	}
+	e.inContact = hit
+}
+
+// emit queues an event for the next DrainEvents
+func (e *Engine) emit(msgType string, payload interface{}) {
+	e.events = append(e.events, models.WSMessage{Type: msgType, Payload: payload})
+}
+
+// DrainEvents returns and clears the events raised since the last call.
+// Callers stepping the engine should drain it regularly.
+func (e *Engine) DrainEvents() []models.WSMessage {
+	events := e.events
+	e.events = nil
+	return events
 }
 
 // updateWheelVelocities smoothly updates wheel velocities toward target
@@ -155,99 +421,84 @@ func (e *Engine) updateWheelVelocities(dt float64) {
 	maxAngularAccel := e.Constants.MaxAccel / e.Constants.WheelRadius
 	maxDeltaVel := maxAngularAccel * dt
 
-	// Update left wheel velocity
-	leftDiff := e.WheelCommand.LeftVelocity - e.GroundTruth.LeftWheel.Velocity
-	if math.Abs(leftDiff) > maxDeltaVel {
-		if leftDiff > 0 {
-			e.GroundTruth.LeftWheel.Velocity += maxDeltaVel
-		} else {
-			e.GroundTruth.LeftWheel.Velocity -= maxDeltaVel
-		}
-	} else {
-		e.GroundTruth.LeftWheel.Velocity = e.WheelCommand.LeftVelocity
-	}
-
-	// Update right wheel velocity
-	rightDiff := e.WheelCommand.RightVelocity - e.GroundTruth.RightWheel.Velocity
-	if math.Abs(rightDiff) > maxDeltaVel {
-		if rightDiff > 0 {
-			e.GroundTruth.RightWheel.Velocity += maxDeltaVel
-		} else {
-			e.GroundTruth.RightWheel.Velocity -= maxDeltaVel
-		}
-	} else {
-		e.GroundTruth.RightWheel.Velocity = e.WheelCommand.RightVelocity
-	}
+	e.GroundTruth.LeftWheel.Velocity = rampToward(e.GroundTruth.LeftWheel.Velocity, e.WheelCommand.LeftVelocity, maxDeltaVel)
+	e.GroundTruth.RightWheel.Velocity = rampToward(e.GroundTruth.RightWheel.Velocity, e.WheelCommand.RightVelocity, maxDeltaVel)
+	e.GroundTruth.LeftWheel.Voltage, e.GroundTruth.LeftWheel.Current = 0, 0
+	e.GroundTruth.RightWheel.Voltage, e.GroundTruth.RightWheel.Current = 0, 0
 }
 
 // wheelVelocitiesToRobotVelocities converts wheel angular velocities to robot linear/angular velocities
 func (e *Engine) wheelVelocitiesToRobotVelocities(leftWheelVel, rightWheelVel float64) (linearVel, angularVel float64) {
-	// Differential drive kinematics:
-	// v = R/2 * (ωL + ωR)
-	// ω = R/L * (ωL - ωR)
-	// where R = wheel radius, L = wheelbase, ωL/ωR = left/right wheel angular velocities
-	linearVel = (e.Constants.WheelRadius / 2.0) * (leftWheelVel + rightWheelVel)
-	angularVel = (e.Constants.WheelRadius / e.Constants.WheelBase) * (leftWheelVel - rightWheelVel)
-
-	// clip to [-maxSpeed, maxSpeed]
-	if linearVel > e.Constants.MaxSpeed {
-		linearVel = e.Constants.MaxSpeed
-	} else if linearVel < -e.Constants.MaxSpeed {
-		linearVel = -e.Constants.MaxSpeed
-	}
+	return wheelToRobotVelocities(e.Constants, leftWheelVel, rightWheelVel)
+}
 
-	if angularVel > e.Constants.MaxSpeed {
-		angularVel = e.Constants.MaxSpeed
-	} else if angularVel < -e.Constants.MaxSpeed {
-		angularVel = -e.Constants.MaxSpeed
+// applySlippage returns the robot velocities produced by what each wheel
+// transmits to the ground. A wheel's contact speed trails its surface speed
+// by a creep slip that grows on low-friction terrain and with the
+// acceleration demanded of the wheel, and it can change no faster than the
+// terrain's traction allows, so wheels spin or skid on ice.
+func (e *Engine) applySlippage(dt float64) (slippedLinear, slippedAngular float64) {
+	c := e.Constants
+	gt := &e.GroundTruth
+
+	// A faster left wheel turns the robot counter-clockwise, so the left
+	// wheel sits on the robot's right-hand side
+	offsetX := c.WheelBase / 2 * math.Sin(gt.Theta)
+	offsetY := -c.WheelBase / 2 * math.Cos(gt.Theta)
+
+	wheels := [2]*models.WheelState{&gt.LeftWheel, &gt.RightWheel}
+	sides := [2]float64{1, -1}
+	for i, wheel := range wheels {
+		friction := e.friction(gt.X+sides[i]*offsetX, gt.Y+sides[i]*offsetY)
+		traction := friction * gravity
+		demand := math.Abs(e.wheelAccel[i]) * c.WheelRadius / traction
+
+		creep := c.SlippageAmount * slipScale * (models.DefaultFriction / friction) * (1 + demand)
+		creep = math.Max(0, math.Min(creep*(1+slipNoise*e.rand.NormFloat64()), 1))
+
+		surface := wheel.Velocity * c.WheelRadius
+		maxChange := traction * dt
+		change := surface*(1-creep) - e.groundSpeed[i]
+		e.groundSpeed[i] += math.Max(-maxChange, math.Min(change, maxChange))
+
+		wheel.Slip = 0
+		if surface != 0 {
+			wheel.Slip = 1 - e.groundSpeed[i]/surface
+		}
 	}
 
-	return
+	return wheelToRobotVelocities(c, e.groundSpeed[0]/c.WheelRadius, e.groundSpeed[1]/c.WheelRadius)
 }
 
-// applySlippage adds noise to velocities proportional to speed and acceleration
-func (e *Engine) applySlippage(linearVel, angularVel, dt float64) (slippedLinear, slippedAngular float64) {
-
-	linearNoise := (e.rand.NormFloat64() - 0.5) * 0.1
-	angularNoise := (e.rand.NormFloat64() - 0.5) * 0.05
-
-	slippedLinear = linearVel * (1 - (e.Constants.SlippageAmount+linearNoise)*0.3)
-	slippedAngular = angularVel * (1 - (e.Constants.SlippageAmount+angularNoise)*0.03)
-	return
+// friction returns the friction coefficient of the terrain at (x, y)
+func (e *Engine) friction(x, y float64) float64 {
+	if e.World == nil {
+		return models.DefaultFriction
+	}
+	return e.World.Friction(x, y)
 }
 
 // updatePosition updates position based on velocities (Euler integration)
 func (e *Engine) updatePosition(state *models.RobotState, linearVel, angularVel, dt float64) {
-	// For small angular velocities, use straight-line approximation
-	if math.Abs(angularVel) < 1e-6 {
-		state.X += linearVel * math.Cos(state.Theta) * dt
-		state.Y += linearVel * math.Sin(state.Theta) * dt
-	} else {
-		// Arc-based motion for non-zero angular velocity
-		// More accurate than simple Euler integration
-		radius := linearVel / angularVel
-		dTheta := angularVel * dt
-		state.X += radius * (math.Sin(state.Theta+dTheta) - math.Sin(state.Theta))
-		state.Y += radius * (-math.Cos(state.Theta+dTheta) + math.Cos(state.Theta))
-		state.Theta += dTheta
-	}
-
-	// Normalize theta to [0, 2π)
-	state.Theta = normalizeAngle(state.Theta)
+	state.X, state.Y, state.Theta = advancePose(state.X, state.Y, state.Theta, linearVel, angularVel, dt)
 }
 
-// updateOdometry updates the odometry estimate based on wheel rotations
-func (e *Engine) updateOdometry(dt float64) {
-	// Odometry uses the commanded/ideal wheel velocities (no slippage)
-	// This simulates reading from wheel encoders
+// updateOdometry dead-reckons from the encoder tick deltas of this step. The
+// encoders only see wheel rotation, so slippage goes unnoticed.
+func (e *Engine) updateOdometry(left, right models.WheelState, dt, simTime float64) {
+	config := e.encoderConfig()
+	dLeft, dRight := e.encoders.sample(config, left, right, simTime)
 
-	// Update odometry wheel velocities (track commanded velocities)
-	e.Odometry.LeftWheel.Velocity = e.GroundTruth.LeftWheel.Velocity
-	e.Odometry.RightWheel.Velocity = e.GroundTruth.RightWheel.Velocity
+	radPerTick := 0.0
+	if config.TicksPerRev > 0 {
+		radPerTick = 2 * math.Pi / float64(config.TicksPerRev)
+	}
 
-	// Update odometry wheel rotations
-	e.Odometry.LeftWheel.Rotation += e.Odometry.LeftWheel.Velocity * dt
-	e.Odometry.RightWheel.Rotation += e.Odometry.RightWheel.Velocity * dt
+	// Wheel velocities are the tick deltas over the step
+	e.Odometry.LeftWheel.Velocity = float64(dLeft) * radPerTick / dt
+	e.Odometry.RightWheel.Velocity = float64(dRight) * radPerTick / dt
+	e.Odometry.LeftWheel.Rotation = float64(e.encoders.left.count) * radPerTick
+	e.Odometry.RightWheel.Rotation = float64(e.encoders.right.count) * radPerTick
 
 	// Calculate robot velocities from wheel velocities
 	linearVel, angularVel := e.wheelVelocitiesToRobotVelocities(
@@ -273,6 +524,24 @@ func (e *Engine) updateOdometry(dt float64) {
 	e.Odometry.Theta = normalizeAngle(e.Odometry.Theta)
 }
 
+// encoderConfig returns the encoder model, falling back to the default
+func (e *Engine) encoderConfig() models.EncoderConfig {
+	if e.Constants.Encoders != nil {
+		return *e.Constants.Encoders
+	}
+	return models.DefaultEncoderConfig()
+}
+
+// Encoders returns the raw encoder counters latched on the last step
+func (e *Engine) Encoders() *models.EncoderReading {
+	return e.encoders.reading(e.encoderConfig().TicksPerRev)
+}
+
+// simTimestamp converts seconds of simulated time into a timestamp
+func simTimestamp(simTime float64) time.Time {
+	return simEpoch.Add(time.Duration(simTime * float64(time.Second)))
+}
+
 // normalizeAngle keeps angle in [0, 2π)
 func normalizeAngle(theta float64) float64 {
 	twoPi := 2 * math.Pi
@@ -289,3 +558,59 @@ func normalizeAngle(theta float64) float64 {
 func (e *Engine) GetState() (models.RobotState, models.OdometryEstimate) {
 	return e.GroundTruth, e.Odometry
 }
+
+// rangeMap returns the world as a RangeMap, or nil without a world
+func (e *Engine) rangeMap() RangeMap {
+	if e.World == nil {
+		return nil
+	}
+	return e.World
+}
+
+// Estimate returns the filtered pose estimate, or nil without an estimator
+func (e *Engine) Estimate() *models.PoseEstimate {
+	if e.Estimator == nil {
+		return nil
+	}
+	estimate := e.Estimator.Estimate()
+	return &estimate
+}
+
+// Particles returns a downsampled particle set when the active estimator is
+// a particle filter, or nil
+func (e *Engine) Particles() *models.ParticlesPayload {
+	source, ok := e.Estimator.(ParticleSource)
+	if !ok {
+		return nil
+	}
+
+	count := models.DefaultParticleFilterConfig().BroadcastCount
+	if e.Constants.ParticleFilter != nil {
+		count = e.Constants.ParticleFilter.BroadcastCount
+	}
+
+	return &models.ParticlesPayload{
+		Total:     source.NumParticles(),
+		Particles: source.Particles(count),
+		SimTime:   e.SimTime,
+	}
+}
+
+// TrajectoryPoint samples the current state for recording
+func (e *Engine) TrajectoryPoint() models.TrajectoryPoint {
+	return models.TrajectoryPoint{
+		Timestamp:     e.GroundTruth.Timestamp,
+		TrueX:         e.GroundTruth.X,
+		TrueY:         e.GroundTruth.Y,
+		TrueTheta:     e.GroundTruth.Theta,
+		OdomX:         e.Odometry.X,
+		OdomY:         e.Odometry.Y,
+		OdomTheta:     e.Odometry.Theta,
+		LinearVel:     e.GroundTruth.LinearVel,
+		AngularVel:    e.GroundTruth.AngularVel,
+		LeftWheelVel:  e.GroundTruth.LeftWheel.Velocity,
+		RightWheelVel: e.GroundTruth.RightWheel.Velocity,
+		Estimate:      e.Estimate(),
+		Tracking:      e.Tracking(),
+	}
+}
@@ -0,0 +1,197 @@
+package simulation
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+// testWorld returns a walled arena with one obstacle, so range sensors and
+// the particle filter have something to measure
+func testWorld(t *testing.T) *world.World {
+	t.Helper()
+	w, err := world.New(models.World{
+		Bounds: &models.Bounds{MinX: -3, MinY: -3, MaxX: 3, MaxY: 3},
+		Obstacles: []models.Obstacle{
+			{Type: "circle", X: 1.5, Y: 0.5, Radius: 0.3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("world.New: %v", err)
+	}
+	return w
+}
+
+// frame is everything an engine exposes about one step
+type frame struct {
+	truth    models.RobotState
+	odometry models.OdometryEstimate
+	estimate *models.PoseEstimate
+	scan     *models.LaserScan
+}
+
+// runScripted drives a fresh engine through a fixed command script and
+// records every step
+func runScripted(t *testing.T, seed int64, estimator string) []frame {
+	t.Helper()
+	e := NewEngineWithSeed(seed)
+	e.SetWorld(testWorld(t))
+	e.UpdateConstants(models.RobotConstants{
+		WheelBase:      e.Constants.WheelBase,
+		WheelRadius:    e.Constants.WheelRadius,
+		MaxSpeed:       e.Constants.MaxSpeed,
+		MaxAccel:       e.Constants.MaxAccel,
+		SlippageAmount: e.Constants.SlippageAmount,
+		Estimator:      estimator,
+	})
+	e.Reset()
+
+	script := []models.WheelCommand{
+		{LeftVelocity: 10, RightVelocity: 10},
+		{LeftVelocity: 12, RightVelocity: 6},
+		{LeftVelocity: -4, RightVelocity: 8},
+		{LeftVelocity: 15, RightVelocity: 15},
+	}
+
+	const dt = 1.0 / 120
+	var frames []frame
+	for _, cmd := range script {
+		e.SetWheelCommand(cmd)
+		for i := 0; i < 120; i++ {
+			e.Step(dt)
+			frames = append(frames, frame{
+				truth:    e.GroundTruth,
+				odometry: e.Odometry,
+				estimate: e.Estimate(),
+				scan:     e.LastScan,
+			})
+		}
+	}
+	return frames
+}
+
+func TestEngineDeterministic(t *testing.T) {
+	estimators := []string{
+		models.EstimatorNone,
+		models.EstimatorEKF,
+		models.EstimatorParticleFilter,
+	}
+
+	for _, estimator := range estimators {
+		t.Run(estimator, func(t *testing.T) {
+			a := runScripted(t, 42, estimator)
+			b := runScripted(t, 42, estimator)
+
+			for i := range a {
+				if !reflect.DeepEqual(a[i], b[i]) {
+					t.Fatalf("step %d differs between runs with the same seed:\n%+v\n%+v", i, a[i], b[i])
+				}
+			}
+			if estimator == models.EstimatorNone && a[len(a)-1].estimate != nil {
+				t.Errorf("estimator %q produced an estimate", estimator)
+			}
+			if estimator != models.EstimatorNone && a[len(a)-1].estimate == nil {
+				t.Errorf("estimator %q produced no estimate", estimator)
+			}
+
+			c := runScripted(t, 43, estimator)
+			if reflect.DeepEqual(a, c) {
+				t.Errorf("runs with different seeds are identical")
+			}
+		})
+	}
+}
+
+func TestEngineResetReproduces(t *testing.T) {
+	e := NewEngineWithSeed(7)
+	e.SetWorld(testWorld(t))
+
+	run := func() models.RobotState {
+		e.Reset()
+		e.SetWheelCommand(models.WheelCommand{LeftVelocity: 14, RightVelocity: 9})
+		for i := 0; i < 240; i++ {
+			e.Step(1.0 / 120)
+		}
+		return e.GroundTruth
+	}
+
+	first := run()
+	if second := run(); first != second {
+		t.Errorf("reset did not reproduce the run:\n%+v\n%+v", first, second)
+	}
+}
+
+func TestTrajectoryPointRecordsOdometryAndEstimate(t *testing.T) {
+	e := NewEngineWithSeed(3)
+	c := e.Constants
+	c.Estimator = models.EstimatorEKF
+	e.UpdateConstants(c)
+	e.SetWheelCommand(models.WheelCommand{LeftVelocity: 10, RightVelocity: 8})
+	for i := 0; i < 120; i++ {
+		e.Step(1.0 / 120)
+	}
+
+	point := e.TrajectoryPoint()
+	if point.OdomX != e.Odometry.X || point.OdomY != e.Odometry.Y || point.OdomTheta != e.Odometry.Theta {
+		t.Errorf("odometry (%g, %g, %g), want (%g, %g, %g)",
+			point.OdomX, point.OdomY, point.OdomTheta, e.Odometry.X, e.Odometry.Y, e.Odometry.Theta)
+	}
+	if point.Estimate == nil || *point.Estimate != *e.Estimate() {
+		t.Errorf("estimate = %+v, want %+v", point.Estimate, e.Estimate())
+	}
+
+	c.Estimator = models.EstimatorNone
+	e.UpdateConstants(c)
+	if point := e.TrajectoryPoint(); point.Estimate != nil {
+		t.Errorf("recorded an estimate without an estimator: %+v", point.Estimate)
+	}
+}
+
+func TestLowFrictionUnderOneWheelTurnsRobot(t *testing.T) {
+	// Ice under the half plane y < 0, which the left wheel of a robot at the
+	// origin heading along +x sits on
+	ice, err := world.New(models.World{
+		Terrain: []models.TerrainZone{{
+			Type:     models.ObstaclePolygon,
+			Friction: 0.05,
+			Points:   []models.Point{{X: -10, Y: -10}, {X: 10, Y: -10}, {X: 10, Y: 0}, {X: -10, Y: 0}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("world.New: %v", err)
+	}
+
+	drive := func(w *world.World) *Engine {
+		e := NewEngineWithSeed(6)
+		c := e.Constants
+		c.SlippageAmount = 0.5
+		e.UpdateConstants(c)
+		e.SetWorld(w)
+		e.Reset()
+		e.SetWheelCommand(models.WheelCommand{LeftVelocity: 10, RightVelocity: 10})
+		for i := 0; i < 120; i++ {
+			e.Step(1.0 / 120)
+		}
+		return e
+	}
+
+	uniform := drive(nil)
+	iced := drive(ice)
+	if iced.GroundTruth.LeftWheel.Slip <= iced.GroundTruth.RightWheel.Slip {
+		t.Errorf("left wheel slip %g on ice is not above right wheel slip %g",
+			iced.GroundTruth.LeftWheel.Slip, iced.GroundTruth.RightWheel.Slip)
+	}
+	// The slipping left wheel falls behind, so the robot turns clockwise
+	icedHeading, uniformHeading := angleDiff(iced.GroundTruth.Theta, 0), angleDiff(uniform.GroundTruth.Theta, 0)
+	if icedHeading > -5*math.Abs(uniformHeading) || icedHeading > -0.05 {
+		t.Errorf("heading on ice = %g rad, uniform terrain = %g; want a clear clockwise turn",
+			icedHeading, uniformHeading)
+	}
+	// Encoders see the wheels turning equally, so odometry keeps the heading
+	if heading := angleDiff(iced.Odometry.Theta, 0); math.Abs(heading) > 0.01 {
+		t.Errorf("odometry heading = %g, want about 0", heading)
+	}
+}
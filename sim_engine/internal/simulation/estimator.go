@@ -0,0 +1,81 @@
+package simulation
+
+import (
+	"math/rand"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// Estimator fuses motion and sensor data into a pose estimate that runs
+// alongside raw odometry
+type Estimator interface {
+	// Name identifies the estimator in published estimates
+	Name() string
+
+	// Reset re-initializes the estimator at a known pose
+	Reset(x, y, theta float64)
+
+	// Update advances the estimate by one simulation step
+	Update(in EstimatorInput)
+
+	// Estimate returns the current pose estimate
+	Estimate() models.PoseEstimate
+}
+
+// ParticleSource is implemented by estimators that can publish their
+// particle set for visualization
+type ParticleSource interface {
+	// Particles returns at most max particles, evenly downsampled
+	Particles(max int) []models.Particle
+
+	// NumParticles returns the size of the full particle set
+	NumParticles() int
+}
+
+// RangeMap is a map that range measurements can be predicted from
+type RangeMap interface {
+	// Raycast returns the distance from (x, y) along heading to the nearest
+	// obstacle, or maxRange when nothing is hit
+	Raycast(x, y, heading, maxRange float64) float64
+}
+
+// EstimatorInput carries one step's worth of motion and sensor data
+type EstimatorInput struct {
+	Dt        float64
+	Constants models.RobotConstants
+
+	// Wheel angular velocities as measured by the encoders (rad/s)
+	LeftWheelVel  float64
+	RightWheelVel float64
+
+	// Absolute position fix, nil when none arrived this step
+	GNSS *models.GNSSReading
+
+	// Inertial reading, nil when none arrived this step
+	IMU *models.IMUReading
+
+	// Range scan and the map to match it against, nil when unavailable
+	Scan *models.LaserScan
+	Map  RangeMap
+}
+
+// newEstimator creates the estimator selected by the constants, or nil. The
+// estimator draws its noise from rng.
+func newEstimator(c models.RobotConstants, rng *rand.Rand) Estimator {
+	switch c.Estimator {
+	case models.EstimatorEKF:
+		config := models.DefaultEKFConfig()
+		if c.EKF != nil {
+			config = *c.EKF
+		}
+		return NewEKF(config)
+	case models.EstimatorParticleFilter:
+		config := models.DefaultParticleFilterConfig()
+		if c.ParticleFilter != nil {
+			config = *c.ParticleFilter
+		}
+		return NewParticleFilter(config, rng)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,280 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+)
+
+// streamRobot offsets the stream IDs from which the seeds of added robots are
+// derived, clear of the per-engine noise streams
+const streamRobot = 1000
+
+// ErrUnknownRobot is returned when an ID names no robot of the fleet
+var ErrUnknownRobot = errors.New("unknown robot")
+
+// Robot is one robot of a fleet
+type Robot struct {
+	ID string
+	*Engine
+
+	index  int  // Order in which the robot was added, which picks its seed
+	seeded bool // Whether its constants set the seed, which SetSeed then keeps
+}
+
+// UpdateConstants updates the robot's constants like Engine.UpdateConstants.
+// A non-zero seed pins the robot's noise streams, so SetSeed keeps it from
+// then on.
+func (r *Robot) UpdateConstants(constants models.RobotConstants) {
+	if constants.Seed != 0 {
+		r.seeded = true
+	}
+	r.Engine.UpdateConstants(constants)
+}
+
+// Fleet is a set of robots sharing one world. Each robot is a full engine
+// with its own constants, start pose, noise streams and controller; the fleet
+// steps them together and keeps them from driving through each other.
+type Fleet struct {
+	World *world.World // Static obstacles, nil for an empty plane
+
+	robots         []*Robot
+	added          int                // Robots added so far
+	commandTimeout float64            // Watchdog timeout of every robot, 0 when disabled
+	contacts       map[[2]string]bool // Robot pairs that touched last step
+	events         []models.WSMessage // Fleet events raised since the last DrainEvents
+}
+
+// NewFleet creates a fleet with a single robot at the origin, seeded from the
+// clock
+func NewFleet() *Fleet {
+	f := &Fleet{contacts: make(map[[2]string]bool)}
+	f.robots = []*Robot{{ID: f.nextID(), Engine: NewEngine()}}
+	f.added = 1
+	return f
+}
+
+// nextID returns an unused robot ID
+func (f *Fleet) nextID() string {
+	for n := f.added + 1; ; n++ {
+		id := "robot" + strconv.Itoa(n)
+		if _, err := f.Robot(id); err != nil {
+			return id
+		}
+	}
+}
+
+// Robots returns every robot in the order they were added
+func (f *Fleet) Robots() []*Robot {
+	return f.robots
+}
+
+// Primary returns the first robot, which messages without a robot ID address
+func (f *Fleet) Primary() *Robot {
+	return f.robots[0]
+}
+
+// Robot returns the robot with the given ID; an empty ID is the primary robot
+func (f *Fleet) Robot(id string) (*Robot, error) {
+	if id == "" {
+		return f.Primary(), nil
+	}
+	for _, r := range f.robots {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownRobot, id)
+}
+
+// AddRobot adds a robot at its start pose. Unless its constants set a seed,
+// its noise streams are derived from the primary robot's seed, so a fleet run
+// is reproduced by the primary seed alone.
+func (f *Fleet) AddRobot(spec models.RobotSpec) (*Robot, error) {
+	id := spec.ID
+	if id == "" {
+		id = f.nextID()
+	} else if _, err := f.Robot(id); err == nil {
+		return nil, fmt.Errorf("robot %q already exists", id)
+	}
+
+	constants := models.DefaultRobotConstants()
+	if spec.Constants != nil {
+		constants = *spec.Constants
+		if err := constants.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, other := range f.robots {
+		gap := math.Hypot(other.GroundTruth.X-spec.Start.X, other.GroundTruth.Y-spec.Start.Y)
+		if gap < (other.Constants.WheelBase+constants.WheelBase)/2 {
+			return nil, fmt.Errorf("start pose overlaps robot %q", other.ID)
+		}
+	}
+
+	r := &Robot{ID: id, index: f.added}
+	r.Engine = NewEngineWithSeed(f.robotSeed(r.index))
+	r.UpdateConstants(constants)
+	r.StartPose = spec.Start
+	r.SetWorld(f.World)
+	r.SetCommandTimeout(f.commandTimeout)
+	r.Reset()
+	// Join the fleet's clock so events line up
+	r.SimTime = f.Primary().SimTime
+
+	f.robots = append(f.robots, r)
+	f.added++
+	return r, nil
+}
+
+// RemoveRobot removes a robot. The primary robot cannot be removed.
+func (f *Fleet) RemoveRobot(id string) error {
+	for i, r := range f.robots {
+		if r.ID != id {
+			continue
+		}
+		if i == 0 {
+			return fmt.Errorf("the primary robot %q cannot be removed", id)
+		}
+		f.robots = append(f.robots[:i], f.robots[i+1:]...)
+		for pair := range f.contacts {
+			if pair[0] == id || pair[1] == id {
+				delete(f.contacts, pair)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%w %q", ErrUnknownRobot, id)
+}
+
+// robotSeed derives the seed of the robot added index-th from the primary
+// robot's seed
+func (f *Fleet) robotSeed(index int) int64 {
+	return streamSeed(f.Primary().Seed(), streamRobot+int64(index))
+}
+
+// SetWorld replaces the static obstacle map of every robot
+func (f *Fleet) SetWorld(w *world.World) {
+	f.World = w
+	for _, r := range f.robots {
+		r.SetWorld(w)
+	}
+}
+
+// SetSeed reseeds the primary robot with seed and every other robot with a
+// seed derived from it, except robots added with a seed of their own, which
+// keep it. A seed of 0 picks a random seed.
+func (f *Fleet) SetSeed(seed int64) {
+	f.Primary().SetSeed(seed)
+	for _, r := range f.robots[1:] {
+		if !r.seeded {
+			r.SetSeed(f.robotSeed(r.index))
+		}
+	}
+}
+
+// SetCommandTimeout arms the command watchdog of every robot. A timeout of 0
+// disables it.
+func (f *Fleet) SetCommandTimeout(timeout float64) {
+	f.commandTimeout = timeout
+	for _, r := range f.robots {
+		r.SetCommandTimeout(timeout)
+	}
+}
+
+// RescaleCommandTimeout changes the watchdog timeout of every robot without
+// feeding the watchdogs
+func (f *Fleet) RescaleCommandTimeout(timeout float64) {
+	f.commandTimeout = timeout
+	for _, r := range f.robots {
+		r.RescaleCommandTimeout(timeout)
+	}
+}
+
+// Reset returns every robot to its start pose
+func (f *Fleet) Reset() {
+	for _, r := range f.robots {
+		r.Reset()
+	}
+	f.contacts = make(map[[2]string]bool)
+	f.events = nil
+}
+
+// Step advances every robot by one time step, then separates robots that
+// ran into each other
+func (f *Fleet) Step(dt float64) {
+	for _, r := range f.robots {
+		r.Step(dt)
+	}
+	f.separate()
+}
+
+// separate pushes overlapping robots apart along the line between their
+// centers and raises a robotCollision event when two robots first touch. Like
+// an obstacle, a push moves only the ground truth.
+func (f *Fleet) separate() {
+	contacts := make(map[[2]string]bool)
+	for i, a := range f.robots {
+		for _, b := range f.robots[i+1:] {
+			ra, rb := a.Constants.WheelBase/2, b.Constants.WheelBase/2
+			dx, dy := b.GroundTruth.X-a.GroundTruth.X, b.GroundTruth.Y-a.GroundTruth.Y
+			d := math.Hypot(dx, dy)
+			overlap := ra + rb - d
+			if overlap <= 0 {
+				continue
+			}
+
+			nx, ny := 1.0, 0.0
+			if d > 1e-9 {
+				nx, ny = dx/d, dy/d
+			}
+			a.push(-nx*overlap/2, -ny*overlap/2)
+			b.push(nx*overlap/2, ny*overlap/2)
+
+			pair := [2]string{a.ID, b.ID}
+			contacts[pair] = true
+			if !f.contacts[pair] {
+				f.events = append(f.events, models.WSMessage{
+					Type: models.MsgTypeRobotCollision,
+					Payload: models.RobotCollisionPayload{
+						RobotA:  a.ID,
+						RobotB:  b.ID,
+						X:       a.GroundTruth.X + nx*ra,
+						Y:       a.GroundTruth.Y + ny*ra,
+						SimTime: a.SimTime,
+					},
+				})
+			}
+		}
+	}
+	f.contacts = contacts
+}
+
+// push displaces the robot's ground truth, keeping it out of the world
+func (r *Robot) push(dx, dy float64) {
+	gt := &r.GroundTruth
+	gt.X += dx
+	gt.Y += dy
+	if r.World != nil {
+		gt.X, gt.Y, _, _, _ = r.World.Resolve(gt.X, gt.Y, r.Constants.WheelBase/2)
+	}
+}
+
+// DrainEvents returns and clears the events of every robot, tagged with the
+// robot's ID, followed by the fleet's own events
+func (f *Fleet) DrainEvents() []models.WSMessage {
+	var events []models.WSMessage
+	for _, r := range f.robots {
+		for _, event := range r.DrainEvents() {
+			event.RobotID = r.ID
+			events = append(events, event)
+		}
+	}
+	events = append(events, f.events...)
+	f.events = nil
+	return events
+}
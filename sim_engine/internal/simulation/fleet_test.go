@@ -0,0 +1,109 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// robotCollisions returns the robot collision events among events
+func robotCollisions(events []models.WSMessage) []models.RobotCollisionPayload {
+	var out []models.RobotCollisionPayload
+	for _, event := range events {
+		if event.Type == models.MsgTypeRobotCollision {
+			out = append(out, event.Payload.(models.RobotCollisionPayload))
+		}
+	}
+	return out
+}
+
+func TestFleetSeparatesOverlappingRobots(t *testing.T) {
+	f := NewFleet()
+	f.SetSeed(1)
+	a := f.Primary()
+	b, err := f.AddRobot(models.RobotSpec{Start: models.Pose{X: 2}})
+	if err != nil {
+		t.Fatalf("AddRobot: %v", err)
+	}
+	minGap := (a.Constants.WheelBase + b.Constants.WheelBase) / 2
+
+	b.GroundTruth.X, b.GroundTruth.Y = 0.1, 0
+	f.separate()
+	if gap := math.Hypot(b.GroundTruth.X-a.GroundTruth.X, b.GroundTruth.Y-a.GroundTruth.Y); gap < minGap-1e-9 {
+		t.Errorf("robots are %g m apart after separating, want at least %g", gap, minGap)
+	}
+	// Each robot takes half of the push
+	if math.Abs(a.GroundTruth.X+b.GroundTruth.X-0.1) > 1e-9 {
+		t.Errorf("robots at x = %g and %g did not share the push", a.GroundTruth.X, b.GroundTruth.X)
+	}
+
+	collisions := robotCollisions(f.DrainEvents())
+	if len(collisions) != 1 {
+		t.Fatalf("got %d robot collisions, want 1", len(collisions))
+	}
+	if collisions[0].RobotA != a.ID || collisions[0].RobotB != b.ID {
+		t.Errorf("collision between %q and %q, want %q and %q", collisions[0].RobotA, collisions[0].RobotB, a.ID, b.ID)
+	}
+
+	// Staying in contact is the same collision
+	b.GroundTruth.X -= 0.05
+	f.separate()
+	if n := len(robotCollisions(f.DrainEvents())); n != 0 {
+		t.Errorf("continued contact raised %d more collisions", n)
+	}
+}
+
+func TestFleetSetSeedKeepsExplicitSeeds(t *testing.T) {
+	f := NewFleet()
+	derived, err := f.AddRobot(models.RobotSpec{Start: models.Pose{X: 1}})
+	if err != nil {
+		t.Fatalf("AddRobot: %v", err)
+	}
+	constants := models.DefaultRobotConstants()
+	constants.Seed = 42
+	explicit, err := f.AddRobot(models.RobotSpec{Constants: &constants, Start: models.Pose{X: 2}})
+	if err != nil {
+		t.Fatalf("AddRobot: %v", err)
+	}
+
+	f.SetSeed(7)
+	if got, want := derived.Seed(), f.robotSeed(derived.index); got != want {
+		t.Errorf("derived seed = %d, want %d", got, want)
+	}
+	if got := explicit.Seed(); got != 42 {
+		t.Errorf("explicit seed = %d, want 42", got)
+	}
+
+	// Setting a seed later pins it the same way
+	update := models.DefaultRobotConstants()
+	update.Seed = 99
+	derived.UpdateConstants(update)
+	f.SetSeed(8)
+	if got := derived.Seed(); got != 99 {
+		t.Errorf("updated seed = %d, want 99", got)
+	}
+}
+
+func TestFleetAddRobotRejectsInvalidSpecs(t *testing.T) {
+	f := NewFleet()
+	lidar := models.DefaultLidarConfig()
+	lidar.UpdateEvery = 0
+	badLidar := models.DefaultRobotConstants()
+	badLidar.Lidar = &lidar
+
+	specs := map[string]models.RobotSpec{
+		"zero wheel base":       {Constants: &models.RobotConstants{WheelRadius: 0.05}, Start: models.Pose{X: 2}},
+		"invalid lidar":         {Constants: &badLidar, Start: models.Pose{X: 2}},
+		"overlapping the first": {Start: models.Pose{X: 0.05}},
+		"duplicate ID":          {ID: f.Primary().ID, Start: models.Pose{X: 2}},
+	}
+	for name, spec := range specs {
+		if _, err := f.AddRobot(spec); err == nil {
+			t.Errorf("%s: AddRobot accepted %+v", name, spec)
+		}
+	}
+	if n := len(f.Robots()); n != 1 {
+		t.Errorf("fleet has %d robots, want 1", n)
+	}
+}
@@ -0,0 +1,133 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// wheelToRobotVelocities converts wheel angular velocities to robot
+// linear/angular velocities for a robot with the given constants
+func wheelToRobotVelocities(c models.RobotConstants, leftWheelVel, rightWheelVel float64) (linearVel, angularVel float64) {
+	// Differential drive kinematics:
+	// v = R/2 * (ωL + ωR)
+	// ω = R/L * (ωL - ωR)
+	// where R = wheel radius, L = wheelbase, ωL/ωR = left/right wheel angular velocities.
+	// A faster left wheel turns the robot counterclockwise (positive ω). This
+	// is the opposite of the frontend's robotUtils.ts, where ω = R/L * (ωR - ωL).
+	linearVel = (c.WheelRadius / 2.0) * (leftWheelVel + rightWheelVel)
+	angularVel = (c.WheelRadius / c.WheelBase) * (leftWheelVel - rightWheelVel)
+
+	// clip to [-maxSpeed, maxSpeed]
+	if linearVel > c.MaxSpeed {
+		linearVel = c.MaxSpeed
+	} else if linearVel < -c.MaxSpeed {
+		linearVel = -c.MaxSpeed
+	}
+
+	if angularVel > c.MaxSpeed {
+		angularVel = c.MaxSpeed
+	} else if angularVel < -c.MaxSpeed {
+		angularVel = -c.MaxSpeed
+	}
+
+	return
+}
+
+// robotToWheelVelocities converts robot linear/angular velocities to wheel
+// angular velocities, inverting wheelToRobotVelocities. It uses the engine's
+// sign convention, so its wheel speeds are swapped relative to the frontend's
+// robotVelocitiesToWheelVelocities for the same twist. The twist is first
+// clipped like the forward kinematics, then scaled down as a whole until no
+// wheel rim exceeds MaxSpeed, which keeps the turning radius. It returns the
+// twist that was actually applied.
+func robotToWheelVelocities(c models.RobotConstants, linearVel, angularVel float64) (leftWheelVel, rightWheelVel, appliedLinear, appliedAngular float64) {
+	linearVel = math.Max(-c.MaxSpeed, math.Min(linearVel, c.MaxSpeed))
+	angularVel = math.Max(-c.MaxSpeed, math.Min(angularVel, c.MaxSpeed))
+
+	// ωL = (v + ω·L/2)/R and ωR = (v - ω·L/2)/R, where the rim speeds below
+	// are the numerators
+	leftRim := linearVel + angularVel*c.WheelBase/2
+	rightRim := linearVel - angularVel*c.WheelBase/2
+	if fastest := math.Max(math.Abs(leftRim), math.Abs(rightRim)); fastest > c.MaxSpeed {
+		scale := c.MaxSpeed / fastest
+		leftRim *= scale
+		rightRim *= scale
+		linearVel *= scale
+		angularVel *= scale
+	}
+
+	return leftRim / c.WheelRadius, rightRim / c.WheelRadius, linearVel, angularVel
+}
+
+// twistToWheelCommand converts a twist into a wheel command, returning the
+// twist that survived the speed limits
+func twistToWheelCommand(c models.RobotConstants, twist models.TwistCommand) (models.WheelCommand, models.TwistCommand) {
+	left, right, v, w := robotToWheelVelocities(c, twist.LinearVel, twist.AngularVel)
+	return models.WheelCommand{LeftVelocity: left, RightVelocity: right},
+		models.TwistCommand{LinearVel: v, AngularVel: w}
+}
+
+// advancePose integrates a pose along the arc described by constant
+// linear/angular velocities over dt. The returned heading is normalized.
+func advancePose(x, y, theta, linearVel, angularVel, dt float64) (float64, float64, float64) {
+	// For small angular velocities, use straight-line approximation
+	if math.Abs(angularVel) < 1e-6 {
+		x += linearVel * math.Cos(theta) * dt
+		y += linearVel * math.Sin(theta) * dt
+	} else {
+		// Arc-based motion for non-zero angular velocity
+		// More accurate than simple Euler integration
+		radius := linearVel / angularVel
+		dTheta := angularVel * dt
+		x += radius * (math.Sin(theta+dTheta) - math.Sin(theta))
+		y += radius * (-math.Cos(theta+dTheta) + math.Cos(theta))
+		theta += dTheta
+	}
+
+	// Normalize theta to [0, 2π)
+	return x, y, normalizeAngle(theta)
+}
+
+// rampToward moves current toward target by at most maxDelta
+func rampToward(current, target, maxDelta float64) float64 {
+	diff := target - current
+	if math.Abs(diff) <= maxDelta {
+		return target
+	}
+	if diff > 0 {
+		return current + maxDelta
+	}
+	return current - maxDelta
+}
+
+// MotionState is the noise-free drivetrain state used for motion prediction
+type MotionState struct {
+	X             float64 `json:"x"`
+	Y             float64 `json:"y"`
+	Theta         float64 `json:"theta"`
+	LeftWheelVel  float64 `json:"leftWheelVel"`  // Left wheel angular velocity in rad/s
+	RightWheelVel float64 `json:"rightWheelVel"` // Right wheel angular velocity in rad/s
+}
+
+// PredictMotion advances s by dt under cmd through the engine's wheel
+// acceleration limit and kinematics, without slip, motors or collisions. It
+// lets planners roll out commands the way the simulated robot executes them.
+func PredictMotion(c models.RobotConstants, s MotionState, cmd models.WheelCommand, dt float64) MotionState {
+	maxDeltaVel := c.MaxAccel / c.WheelRadius * dt
+	s.LeftWheelVel = rampToward(s.LeftWheelVel, cmd.LeftVelocity, maxDeltaVel)
+	s.RightWheelVel = rampToward(s.RightWheelVel, cmd.RightVelocity, maxDeltaVel)
+
+	v, w := wheelToRobotVelocities(c, s.LeftWheelVel, s.RightWheelVel)
+	s.X, s.Y, s.Theta = advancePose(s.X, s.Y, s.Theta, v, w, dt)
+	return s
+}
+
+// angleDiff returns the signed difference a-b wrapped to [-π, π)
+func angleDiff(a, b float64) float64 {
+	d := math.Mod(a-b+math.Pi, 2*math.Pi)
+	if d < 0 {
+		d += 2 * math.Pi
+	}
+	return d - math.Pi
+}
@@ -0,0 +1,45 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+func TestRobotToWheelVelocitiesInvertsForwardKinematics(t *testing.T) {
+	c := models.DefaultRobotConstants()
+
+	twists := [][2]float64{{0.5, 0}, {0, 1}, {0.3, -0.8}, {-0.4, 0.5}}
+	for _, twist := range twists {
+		left, right, v, w := robotToWheelVelocities(c, twist[0], twist[1])
+		if v != twist[0] || w != twist[1] {
+			t.Errorf("twist %v was limited to (%g, %g)", twist, v, w)
+		}
+		gotV, gotW := wheelToRobotVelocities(c, left, right)
+		if math.Abs(gotV-twist[0]) > 1e-12 || math.Abs(gotW-twist[1]) > 1e-12 {
+			t.Errorf("twist %v -> wheels (%g, %g) -> (%g, %g)", twist, left, right, gotV, gotW)
+		}
+	}
+}
+
+func TestPositiveTurnRateSpeedsUpLeftWheel(t *testing.T) {
+	c := models.DefaultRobotConstants()
+	left, right, _, _ := robotToWheelVelocities(c, 0, 1)
+	if left <= 0 || right >= 0 {
+		t.Errorf("turning counterclockwise in place gave wheels (%g, %g), want left forward and right back", left, right)
+	}
+}
+
+func TestRobotToWheelVelocitiesKeepsTurningRadius(t *testing.T) {
+	c := models.DefaultRobotConstants()
+	const v, w = 1.8, 1.5 // The outer rim would exceed MaxSpeed
+
+	left, right, appliedV, appliedW := robotToWheelVelocities(c, v, w)
+	if rim := math.Max(math.Abs(left), math.Abs(right)) * c.WheelRadius; rim > c.MaxSpeed+1e-12 {
+		t.Errorf("fastest rim moves at %g m/s, above MaxSpeed %g", rim, c.MaxSpeed)
+	}
+	if math.Abs(appliedV/appliedW-v/w) > 1e-12 {
+		t.Errorf("turning radius changed from %g to %g", v/w, appliedV/appliedW)
+	}
+}
@@ -0,0 +1,100 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// motorConfig returns the motor model when it is enabled, or nil
+func (e *Engine) motorConfig() *models.MotorConfig {
+	if m := e.Constants.Motor; m != nil && m.Enabled {
+		return m
+	}
+	return nil
+}
+
+// SetMotorCommand drives the motors directly until the next wheel command.
+// It only has an effect while the motor model is enabled, and cancels the
+// active controller.
+func (e *Engine) SetMotorCommand(cmd models.MotorCommand) error {
+	switch cmd.Mode {
+	case "", models.MotorModeVoltage, models.MotorModePWM:
+	default:
+		return fmt.Errorf("unknown motor command mode %q", cmd.Mode)
+	}
+	e.CancelController("manual command")
+	e.MotorCommand = &cmd
+	e.feedWatchdog()
+	return nil
+}
+
+// updateMotors integrates the motor dynamics of both wheels
+func (e *Engine) updateMotors(m models.MotorConfig, dt float64) {
+	left, right := e.motorVoltages(m, dt)
+	stepMotor(m, &e.GroundTruth.LeftWheel, left, dt)
+	stepMotor(m, &e.GroundTruth.RightWheel, right, dt)
+}
+
+// motorVoltages returns the voltage applied to each motor: the motor command
+// when one is active, otherwise the output of the wheel velocity loops, or
+// without them the steady-state voltage of the commanded wheel velocity
+// (open-loop feed-forward)
+func (e *Engine) motorVoltages(m models.MotorConfig, dt float64) (left, right float64) {
+	if pid := e.pidConfig(); pid != nil && e.MotorCommand == nil {
+		return e.runPID(*pid, m, dt)
+	}
+	e.resetPID()
+
+	if cmd := e.MotorCommand; cmd != nil {
+		if cmd.Mode == models.MotorModePWM {
+			return cmd.Left * m.SupplyVoltage, cmd.Right * m.SupplyVoltage
+		}
+		return cmd.Left, cmd.Right
+	}
+	return steadyStateVoltage(m, e.WheelCommand.LeftVelocity), steadyStateVoltage(m, e.WheelCommand.RightVelocity)
+}
+
+// resetPID clears the wheel velocity loops while they are not running, so
+// they start fresh when they take over again
+func (e *Engine) resetPID() {
+	e.pid = [2]pidController{}
+	e.pidState = nil
+}
+
+// steadyStateVoltage returns the voltage that holds a free wheel at velocity,
+// where the motor torque balances viscous friction
+func steadyStateVoltage(m models.MotorConfig, velocity float64) float64 {
+	if m.Kt == 0 {
+		return m.Ke * velocity
+	}
+	return velocity * (m.Ke + m.Resistance*m.ViscousFriction/m.Kt)
+}
+
+// stepMotor advances one wheel by dt with voltage applied to its motor. The
+// armature inductance is neglected, so the current follows the voltage
+// instantly: i = (V - Ke ω) / R and J dω/dt = Kt i - b ω.
+func stepMotor(m models.MotorConfig, wheel *models.WheelState, voltage, dt float64) {
+	voltage = math.Max(-m.SupplyVoltage, math.Min(voltage, m.SupplyVoltage))
+	wheel.Voltage = voltage
+	if m.Resistance <= 0 || m.Inertia <= 0 {
+		return
+	}
+
+	current := (voltage - m.Ke*wheel.Velocity) / m.Resistance
+	if m.MaxCurrent > 0 && math.Abs(current) > m.MaxCurrent {
+		// The driver limits the current, so the torque is constant
+		current = math.Copysign(m.MaxCurrent, current)
+		wheel.Velocity += (m.Kt*current - m.ViscousFriction*wheel.Velocity) / m.Inertia * dt
+	} else {
+		// The unlimited dynamics are linear, so integrate them exactly:
+		// ω approaches ω∞ with rate k
+		k := (m.Kt*m.Ke/m.Resistance + m.ViscousFriction) / m.Inertia
+		if k > 0 {
+			target := m.Kt * voltage / (m.Resistance * m.Inertia * k)
+			wheel.Velocity = target + (wheel.Velocity-target)*math.Exp(-k*dt)
+		}
+	}
+	wheel.Current = current
+}
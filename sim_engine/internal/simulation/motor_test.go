@@ -0,0 +1,118 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// motorEngine returns an engine with the motor model enabled and the wheel
+// velocity loops off
+func motorEngine(t *testing.T) *Engine {
+	t.Helper()
+	e := NewEngineWithSeed(1)
+	constants := models.DefaultRobotConstants()
+	constants.Motor.Enabled = true
+	constants.PID.Enabled = false
+	if err := constants.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	e.UpdateConstants(constants)
+	return e
+}
+
+func TestMotorStepResponse(t *testing.T) {
+	m := models.DefaultMotorConfig()
+	m.MaxCurrent = 0
+	const voltage, dt = 6.0, 0.01
+	steady := voltage * m.Kt / (m.Kt*m.Ke + m.Resistance*m.ViscousFriction)
+	tau := m.Inertia / (m.Kt*m.Ke/m.Resistance + m.ViscousFriction)
+
+	var wheel models.WheelState
+	steps := int(math.Round(tau / dt))
+	for i := 0; i < steps; i++ {
+		stepMotor(m, &wheel, voltage, dt)
+	}
+	// A first-order response reaches 1 - 1/e of its final value after one
+	// time constant
+	want := steady * (1 - math.Exp(-float64(steps)*dt/tau))
+	if math.Abs(wheel.Velocity-want) > 1e-9*steady {
+		t.Errorf("velocity after %g s = %g rad/s, want %g", float64(steps)*dt, wheel.Velocity, want)
+	}
+
+	for i := 0; i < 500; i++ {
+		stepMotor(m, &wheel, voltage, dt)
+	}
+	if math.Abs(wheel.Velocity-steady) > 1e-3*steady {
+		t.Errorf("steady-state velocity = %g rad/s, want %g", wheel.Velocity, steady)
+	}
+	// At steady state the motor torque only balances viscous friction
+	if want := m.ViscousFriction * steady / m.Kt; math.Abs(wheel.Current-want) > 1e-3*want {
+		t.Errorf("current = %g A, want %g", wheel.Current, want)
+	}
+	if wheel.Voltage != voltage {
+		t.Errorf("voltage = %g V, want %g", wheel.Voltage, voltage)
+	}
+}
+
+func TestMotorSaturates(t *testing.T) {
+	m := models.DefaultMotorConfig()
+	const dt = 0.001
+
+	var wheel models.WheelState
+	stepMotor(m, &wheel, 10*m.SupplyVoltage, dt)
+	if wheel.Voltage != m.SupplyVoltage {
+		t.Errorf("voltage = %g V, want the supply voltage %g", wheel.Voltage, m.SupplyVoltage)
+	}
+	// From rest the stall current exceeds the driver limit, so the motor
+	// accelerates with the limited torque
+	if wheel.Current != m.MaxCurrent {
+		t.Errorf("current = %g A, want the limit %g", wheel.Current, m.MaxCurrent)
+	}
+	if want := m.Kt * m.MaxCurrent / m.Inertia * dt; math.Abs(wheel.Velocity-want) > 1e-12 {
+		t.Errorf("velocity = %g rad/s, want %g", wheel.Velocity, want)
+	}
+
+	stepMotor(m, &wheel, -10*m.SupplyVoltage, dt)
+	if wheel.Voltage != -m.SupplyVoltage || wheel.Current != -m.MaxCurrent {
+		t.Errorf("reversed to %g V and %g A, want %g V and %g A", wheel.Voltage, wheel.Current, -m.SupplyVoltage, -m.MaxCurrent)
+	}
+}
+
+func TestMotorCommandDrivesWheels(t *testing.T) {
+	e := motorEngine(t)
+	m := *e.Constants.Motor
+
+	if err := e.SetMotorCommand(models.MotorCommand{Mode: "current", Left: 1, Right: 1}); err == nil {
+		t.Error("SetMotorCommand accepted an unknown mode")
+	}
+	if e.MotorCommand != nil {
+		t.Fatalf("a rejected command was applied: %+v", e.MotorCommand)
+	}
+
+	// Half duty cycle applies half the supply voltage
+	if err := e.SetMotorCommand(models.MotorCommand{Mode: models.MotorModePWM, Left: 0.5, Right: -0.5}); err != nil {
+		t.Fatalf("SetMotorCommand: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		e.Step(0.01)
+	}
+	left, right := e.GroundTruth.LeftWheel, e.GroundTruth.RightWheel
+	if left.Voltage != m.SupplyVoltage/2 || right.Voltage != -m.SupplyVoltage/2 {
+		t.Errorf("voltages = %g and %g V, want ±%g", left.Voltage, right.Voltage, m.SupplyVoltage/2)
+	}
+	if left.Velocity <= 0 || right.Velocity >= 0 {
+		t.Errorf("wheel velocities = %g and %g rad/s, want opposite signs", left.Velocity, right.Velocity)
+	}
+
+	// A wheel command takes back control from the motor command
+	e.SetWheelCommand(models.WheelCommand{LeftVelocity: 5, RightVelocity: 5})
+	if e.MotorCommand != nil {
+		t.Error("the wheel command did not end the motor command")
+	}
+	e.Step(0.01)
+	if want := steadyStateVoltage(m, 5); math.Abs(e.GroundTruth.LeftWheel.Voltage-want) > 1e-12 {
+		t.Errorf("voltage = %g V after the wheel command, want the feed-forward %g", e.GroundTruth.LeftWheel.Voltage, want)
+	}
+}
@@ -0,0 +1,213 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+const (
+	// navProgressInterval is the simulated time between progress events
+	navProgressInterval = 0.1
+
+	// navStallTime is how long the navigator may go without getting closer
+	// to its waypoint before it gives up
+	navStallTime = 5.0
+
+	// navStallProgress is the improvement, in meters or radians, that counts
+	// as getting closer
+	navStallProgress = 0.01
+)
+
+// navigator drives to a sequence of waypoints with the polar-coordinate
+// control law, closing the loop on a selectable feedback source. Only the
+// last waypoint's heading is honored; earlier ones are passed through.
+type navigator struct {
+	goals            []models.Waypoint
+	feedback         string
+	tolerance        float64
+	headingTolerance float64
+	maxLinearVel     float64
+	timeout          float64
+	gains            models.NavigationGains
+
+	current      int
+	turning      bool    // Whether the robot is turning in place to the final heading
+	started      float64 // Simulated time of the first update, -1 before it
+	nextProgress float64
+	best         float64 // Smallest distance (or heading error) seen for the current goal
+	bestTime     float64 // Simulated time best was last improved
+}
+
+// NewNavigator creates the go-to-pose controller for a navigateTo request
+func NewNavigator(req models.NavigateToPayload) (Controller, error) {
+	goals := req.Waypoints
+	if req.Target != nil {
+		goals = append(append([]models.Waypoint{}, goals...), *req.Target)
+	}
+	if len(goals) == 0 {
+		return nil, errors.New("navigateTo needs a target or waypoints")
+	}
+
+	n := &navigator{
+		goals:            goals,
+		feedback:         req.Feedback,
+		tolerance:        req.Tolerance,
+		headingTolerance: req.HeadingTolerance,
+		maxLinearVel:     req.MaxLinearVel,
+		timeout:          req.Timeout,
+		gains:            models.DefaultNavigationGains(),
+		started:          -1,
+	}
+	if n.feedback == "" {
+		n.feedback = models.FeedbackOdometry
+	}
+	if n.tolerance <= 0 {
+		n.tolerance = 0.05
+	}
+	if n.headingTolerance <= 0 {
+		n.headingTolerance = 0.05
+	}
+	if n.maxLinearVel <= 0 {
+		n.maxLinearVel = 0.5
+	}
+	if n.timeout <= 0 {
+		n.timeout = 120
+	}
+	if req.Gains != nil {
+		n.gains = *req.Gains
+	}
+
+	switch n.feedback {
+	case models.FeedbackGroundTruth, models.FeedbackOdometry, models.FeedbackEstimate:
+	default:
+		return nil, fmt.Errorf("unknown feedback source %q", n.feedback)
+	}
+	if g := n.gains; g.KRho <= 0 || g.KBeta > 0 || g.KAlpha <= g.KRho {
+		return nil, errors.New("gains must satisfy kRho > 0, kBeta <= 0 and kAlpha > kRho")
+	}
+	return n, nil
+}
+
+// Name implements Controller
+func (n *navigator) Name() string {
+	return models.MsgTypeNavigateTo
+}
+
+// Feedback implements Controller
+func (n *navigator) Feedback() string {
+	return n.feedback
+}
+
+// Update implements Controller
+func (n *navigator) Update(in ControllerInput) (models.TwistCommand, bool) {
+	if n.started < 0 {
+		n.started = in.SimTime
+		n.resetStall(in.SimTime)
+	}
+
+	goal := n.goals[n.current]
+	final := n.current == len(n.goals)-1
+	dx, dy := goal.X-in.Pose.X, goal.Y-in.Pose.Y
+	rho := math.Hypot(dx, dy)
+	headingError := 0.0
+	if final && goal.Theta != nil {
+		headingError = angleDiff(*goal.Theta, in.Pose.Theta)
+	}
+
+	event := models.NavigationEvent{
+		Controller:       n.Name(),
+		Feedback:         n.feedback,
+		Waypoint:         n.current,
+		Waypoints:        len(n.goals),
+		Distance:         rho,
+		HeadingError:     headingError,
+		GroundTruthError: math.Hypot(goal.X-in.GroundTruth.X, goal.Y-in.GroundTruth.Y),
+		SimTime:          in.SimTime,
+	}
+
+	if in.SimTime-n.started > n.timeout {
+		event.Event, event.Reason = models.NavigationFailed, "timeout"
+		in.Emit(event)
+		return models.TwistCommand{}, true
+	}
+
+	progress := rho
+	if n.turning {
+		progress = math.Abs(headingError)
+	}
+	if progress < n.best-navStallProgress {
+		n.best, n.bestTime = progress, in.SimTime
+	} else if in.SimTime-n.bestTime > navStallTime {
+		event.Event, event.Reason = models.NavigationFailed, "stalled"
+		in.Emit(event)
+		return models.TwistCommand{}, true
+	}
+
+	if in.SimTime >= n.nextProgress {
+		n.nextProgress = in.SimTime + navProgressInterval
+		event.Event = models.NavigationProgress
+		in.Emit(event)
+	}
+
+	if rho <= n.tolerance || n.turning {
+		if !final {
+			event.Event = models.NavigationWaypointReached
+			in.Emit(event)
+			n.current++
+			n.resetStall(in.SimTime)
+			return n.Update(in)
+		}
+		if math.Abs(headingError) > n.headingTolerance {
+			// Turn in place to the final heading
+			if !n.turning {
+				n.turning = true
+				n.resetStall(in.SimTime)
+			}
+			return models.TwistCommand{AngularVel: n.gains.KAlpha * headingError}, false
+		}
+		event.Event = models.NavigationArrived
+		in.Emit(event)
+		return models.TwistCommand{}, true
+	}
+
+	return n.polarControl(in.Pose, goal, final, rho), false
+}
+
+// polarControl applies v = kRho ρ, ω = kAlpha α + kBeta β, where α is the
+// bearing of the goal relative to the heading and β the angle between the
+// bearing and the goal heading. Goals behind the robot are approached in
+// reverse.
+func (n *navigator) polarControl(pose models.Pose, goal models.Waypoint, final bool, rho float64) models.TwistCommand {
+	bearing := math.Atan2(goal.Y-pose.Y, goal.X-pose.X)
+	alpha := angleDiff(bearing, pose.Theta)
+	direction := 1.0
+	if math.Abs(alpha) > math.Pi/2 {
+		alpha = angleDiff(alpha+math.Pi, 0)
+		direction = -1
+	}
+
+	beta := 0.0
+	if final && goal.Theta != nil {
+		beta = angleDiff(*goal.Theta, pose.Theta+alpha)
+	}
+
+	v := direction * n.gains.KRho * rho
+	w := n.gains.KAlpha*alpha + n.gains.KBeta*beta
+
+	// Slow down along the same arc when over the speed cap
+	if math.Abs(v) > n.maxLinearVel {
+		scale := n.maxLinearVel / math.Abs(v)
+		v *= scale
+		w *= scale
+	}
+	return models.TwistCommand{LinearVel: v, AngularVel: w}
+}
+
+// resetStall restarts stall detection for a new goal or phase
+func (n *navigator) resetStall(simTime float64) {
+	n.best = math.Inf(1)
+	n.bestTime = simTime
+}
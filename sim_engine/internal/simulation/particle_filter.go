@@ -0,0 +1,254 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+const (
+	// pfMinVelocityStd keeps particles diffusing slightly while stationary
+	pfMinVelocityStd = 1e-3
+
+	// pfNoiseInterval is how long (s) each particle keeps its sampled
+	// velocity error. Holding the error over an interval, rather than drawing
+	// it every step, keeps the spread independent of the step size.
+	pfNoiseInterval = 0.1
+
+	// pfRandomRangeWeight mixes a uniform term into the beam likelihood so
+	// that unexpected obstacles and outliers do not zero out a particle
+	pfRandomRangeWeight = 0.05
+)
+
+// ParticleFilter is a Monte Carlo localization filter. Particles are moved
+// with the wheel velocities measured by the encoders under a noisy velocity
+// motion model and weighted by how well range scans match the map from their
+// pose.
+type ParticleFilter struct {
+	config    models.ParticleFilterConfig
+	rand      *rand.Rand
+	particles []models.Particle
+	scratch   []models.Particle
+
+	// Per-particle velocity errors, redrawn every pfNoiseInterval
+	noiseV, noiseW []float64
+	noiseAge       float64
+}
+
+// NewParticleFilter creates a particle filter at the origin
+func NewParticleFilter(config models.ParticleFilterConfig, rng *rand.Rand) *ParticleFilter {
+	if config.NumParticles <= 0 {
+		config.NumParticles = models.DefaultParticleFilterConfig().NumParticles
+	}
+
+	pf := &ParticleFilter{
+		config:    config,
+		rand:      rng,
+		particles: make([]models.Particle, config.NumParticles),
+		scratch:   make([]models.Particle, config.NumParticles),
+		noiseV:    make([]float64, config.NumParticles),
+		noiseW:    make([]float64, config.NumParticles),
+	}
+	pf.Reset(0, 0, 0)
+	return pf
+}
+
+// Name implements Estimator
+func (pf *ParticleFilter) Name() string {
+	return models.EstimatorParticleFilter
+}
+
+// Reset implements Estimator. Particles are spread around the pose by the
+// configured initial position and heading spreads.
+func (pf *ParticleFilter) Reset(x, y, theta float64) {
+	spread := pf.config.InitialSpread
+	headingSpread := pf.config.InitialHeadingSpread
+	weight := 1 / float64(len(pf.particles))
+	for i := range pf.particles {
+		pf.particles[i] = models.Particle{
+			X:      x + pf.rand.NormFloat64()*spread,
+			Y:      y + pf.rand.NormFloat64()*spread,
+			Theta:  normalizeAngle(theta + pf.rand.NormFloat64()*headingSpread),
+			Weight: weight,
+		}
+	}
+	pf.noiseAge = math.Inf(1)
+}
+
+// Update implements Estimator
+func (pf *ParticleFilter) Update(in EstimatorInput) {
+	pf.predict(in)
+	if in.Scan != nil && in.Map != nil {
+		pf.weigh(in.Scan, in.Map)
+		if pf.effectiveSampleSize() < pf.config.ResampleThreshold*float64(len(pf.particles)) {
+			pf.resample()
+		}
+	}
+}
+
+// predict samples a new pose for every particle from the velocity motion
+// model driven by the measured wheel velocities. The commanded velocities
+// would leave the particles behind under motor or PID drive, where the
+// wheels do not follow the wheel command.
+func (pf *ParticleFilter) predict(in EstimatorInput) {
+	v, w := wheelToRobotVelocities(in.Constants, in.LeftWheelVel, in.RightWheelVel)
+	c := pf.config
+	stdV := c.Alpha1*math.Abs(v) + c.Alpha2*math.Abs(w) + pfMinVelocityStd
+	stdW := c.Alpha3*math.Abs(v) + c.Alpha4*math.Abs(w) + pfMinVelocityStd
+
+	redraw := pf.noiseAge >= pfNoiseInterval
+	if redraw {
+		pf.noiseAge = 0
+	}
+	pf.noiseAge += in.Dt
+
+	for i := range pf.particles {
+		if redraw {
+			pf.noiseV[i] = pf.rand.NormFloat64()
+			pf.noiseW[i] = pf.rand.NormFloat64()
+		}
+		p := &pf.particles[i]
+		noisyV := v + pf.noiseV[i]*stdV
+		noisyW := w + pf.noiseW[i]*stdW
+		p.X, p.Y, p.Theta = advancePose(p.X, p.Y, p.Theta, noisyV, noisyW, in.Dt)
+	}
+}
+
+// weigh multiplies every particle weight by the likelihood of the scan from
+// its pose, using a subset of the beams
+func (pf *ParticleFilter) weigh(scan *models.LaserScan, m RangeMap) {
+	if scan.RangeMax <= 0 {
+		// No beam can be valid, and the uniform term below would divide by
+		// zero
+		return
+	}
+
+	stride := 1
+	if pf.config.MaxBeams > 0 && len(scan.Ranges) > pf.config.MaxBeams {
+		stride = (len(scan.Ranges) + pf.config.MaxBeams - 1) / pf.config.MaxBeams
+	}
+
+	std := pf.config.RangeNoiseStd
+	if std <= 0 {
+		std = models.DefaultParticleFilterConfig().RangeNoiseStd
+	}
+	norm := 1 / (std * math.Sqrt(2*math.Pi))
+	uniform := pfRandomRangeWeight / scan.RangeMax
+
+	// Work in log space so long scans do not underflow
+	logWeights := make([]float64, len(pf.particles))
+	best := math.Inf(-1)
+	for i, p := range pf.particles {
+		logW := math.Log(p.Weight)
+		for b := 0; b < len(scan.Ranges); b += stride {
+			measured := scan.Ranges[b]
+			if math.IsNaN(measured) || measured < scan.RangeMin || measured > scan.RangeMax {
+				continue
+			}
+			angle := p.Theta + scan.AngleMin + float64(b)*scan.AngleIncrement
+			expected := m.Raycast(p.X, p.Y, angle, scan.RangeMax)
+			diff := (measured - expected) / std
+			logW += math.Log((1-pfRandomRangeWeight)*norm*math.Exp(-0.5*diff*diff) + uniform)
+		}
+		logWeights[i] = logW
+		best = math.Max(best, logW)
+	}
+
+	total := 0.0
+	for i := range pf.particles {
+		pf.particles[i].Weight = math.Exp(logWeights[i] - best)
+		total += pf.particles[i].Weight
+	}
+	for i := range pf.particles {
+		pf.particles[i].Weight /= total
+	}
+}
+
+// effectiveSampleSize returns 1 / Σw², the number of particles that
+// effectively carry the weight
+func (pf *ParticleFilter) effectiveSampleSize() float64 {
+	sum := 0.0
+	for _, p := range pf.particles {
+		sum += p.Weight * p.Weight
+	}
+	if sum == 0 {
+		return 0
+	}
+	return 1 / sum
+}
+
+// resample draws a new equally weighted set with low-variance resampling
+func (pf *ParticleFilter) resample() {
+	n := len(pf.particles)
+	step := 1 / float64(n)
+	r := pf.rand.Float64() * step
+	c := pf.particles[0].Weight
+	i := 0
+
+	for m := 0; m < n; m++ {
+		u := r + float64(m)*step
+		for u > c && i < n-1 {
+			i++
+			c += pf.particles[i].Weight
+		}
+		pf.scratch[m] = pf.particles[i]
+		pf.scratch[m].Weight = step
+	}
+	pf.particles, pf.scratch = pf.scratch, pf.particles
+}
+
+// Estimate implements Estimator with the weighted mean and covariance of the
+// particle set. The heading uses a circular mean.
+func (pf *ParticleFilter) Estimate() models.PoseEstimate {
+	var x, y, sinSum, cosSum float64
+	for _, p := range pf.particles {
+		x += p.Weight * p.X
+		y += p.Weight * p.Y
+		sinSum += p.Weight * math.Sin(p.Theta)
+		cosSum += p.Weight * math.Cos(p.Theta)
+	}
+	theta := normalizeAngle(math.Atan2(sinSum, cosSum))
+
+	var cov mat3
+	for _, p := range pf.particles {
+		d := [3]float64{p.X - x, p.Y - y, angleDiff(p.Theta, theta)}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				cov[i][j] += p.Weight * d[i] * d[j]
+			}
+		}
+	}
+
+	estimate := models.PoseEstimate{
+		Estimator: pf.Name(),
+		X:         x,
+		Y:         y,
+		Theta:     theta,
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			estimate.Covariance[i*3+j] = cov[i][j]
+		}
+	}
+	return estimate
+}
+
+// Particles implements ParticleSource
+func (pf *ParticleFilter) Particles(max int) []models.Particle {
+	n := len(pf.particles)
+	if max <= 0 || max > n {
+		max = n
+	}
+
+	out := make([]models.Particle, max)
+	for i := range out {
+		out[i] = pf.particles[i*n/max]
+	}
+	return out
+}
+
+// NumParticles implements ParticleSource
+func (pf *ParticleFilter) NumParticles() int {
+	return len(pf.particles)
+}
@@ -0,0 +1,154 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// perfectScan returns a noiseless full-circle scan of m from a pose
+func perfectScan(m RangeMap, x, y, theta float64) *models.LaserScan {
+	const beams = 72
+	scan := &models.LaserScan{
+		AngleMin:       -math.Pi,
+		AngleIncrement: 2 * math.Pi / beams,
+		RangeMin:       0.05,
+		RangeMax:       5,
+		Ranges:         make([]float64, beams),
+	}
+	for b := range scan.Ranges {
+		scan.Ranges[b] = m.Raycast(x, y, theta+scan.AngleMin+float64(b)*scan.AngleIncrement, scan.RangeMax)
+	}
+	return scan
+}
+
+func TestParticleFilterResetSpreads(t *testing.T) {
+	config := models.DefaultParticleFilterConfig()
+	config.NumParticles = 2000
+	config.InitialSpread = 0.5
+	config.InitialHeadingSpread = 0.01
+	pf := NewParticleFilter(config, rand.New(rand.NewSource(1)))
+
+	pf.Reset(1, 2, 0.3)
+	estimate := pf.Estimate()
+
+	stdX := math.Sqrt(estimate.Covariance[0])
+	stdTheta := math.Sqrt(estimate.Covariance[8])
+	if math.Abs(stdX-0.5) > 0.05 {
+		t.Errorf("position spread = %g m, want about 0.5", stdX)
+	}
+	if math.Abs(stdTheta-0.01) > 0.002 {
+		t.Errorf("heading spread = %g rad, want about 0.01", stdTheta)
+	}
+	if math.Abs(estimate.Theta-0.3) > 0.01 {
+		t.Errorf("mean heading = %g, want 0.3", estimate.Theta)
+	}
+}
+
+func TestParticleFilterLocalizesWithScan(t *testing.T) {
+	w := testWorld(t)
+	c := models.DefaultRobotConstants()
+	config := models.DefaultParticleFilterConfig()
+	config.InitialSpread = 0.3
+	config.InitialHeadingSpread = 0.1
+	pf := NewParticleFilter(config, rand.New(rand.NewSource(2)))
+
+	// The filter starts around the wrong pose and the scans come from the
+	// true one, so weighting should pull the estimate over
+	const trueX, trueY = -1.0, 0.5
+	pf.Reset(trueX+0.2, trueY-0.2, 0)
+	scan := perfectScan(w, trueX, trueY, 0)
+	for i := 0; i < 20; i++ {
+		pf.Update(EstimatorInput{Dt: 0.01, Constants: c, Scan: scan, Map: w})
+	}
+
+	estimate := pf.Estimate()
+	if d := math.Hypot(estimate.X-trueX, estimate.Y-trueY); d > 0.1 {
+		t.Errorf("estimate (%g, %g) is %g m from the true pose", estimate.X, estimate.Y, d)
+	}
+}
+
+func TestParticleFilterWeightsNormalized(t *testing.T) {
+	w := testWorld(t)
+	pf := NewParticleFilter(models.DefaultParticleFilterConfig(), rand.New(rand.NewSource(3)))
+	pf.Reset(0, 0, 0)
+	pf.weigh(perfectScan(w, 0, 0, 0), w)
+
+	total := 0.0
+	for _, p := range pf.particles {
+		total += p.Weight
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("weights sum to %g, want 1", total)
+	}
+}
+
+func TestUpdateConstantsKeepsEstimatorForSameParticleFilterConfig(t *testing.T) {
+	e := NewEngineWithSeed(1)
+	e.UpdateConstants(models.RobotConstants{
+		WheelBase:   e.Constants.WheelBase,
+		WheelRadius: e.Constants.WheelRadius,
+		Estimator:   models.EstimatorParticleFilter,
+	})
+	estimator := e.Estimator
+
+	same := *e.Constants.ParticleFilter
+	e.UpdateConstants(models.RobotConstants{
+		WheelBase:      e.Constants.WheelBase,
+		WheelRadius:    e.Constants.WheelRadius,
+		ParticleFilter: &same,
+	})
+	if e.Estimator != estimator {
+		t.Error("an identical particle filter config restarted the estimator")
+	}
+}
+
+func TestParticleFilterIgnoresScanWithoutRange(t *testing.T) {
+	w := testWorld(t)
+	pf := NewParticleFilter(models.DefaultParticleFilterConfig(), rand.New(rand.NewSource(4)))
+	pf.Reset(0, 0, 0)
+
+	scan := perfectScan(w, 0, 0, 0)
+	scan.RangeMax = 0
+	pf.weigh(scan, w)
+
+	for i, p := range pf.particles {
+		if math.IsNaN(p.Weight) || math.IsInf(p.Weight, 0) {
+			t.Fatalf("particle %d has weight %g", i, p.Weight)
+		}
+	}
+	if estimate := pf.Estimate(); math.IsNaN(estimate.X) || math.IsNaN(estimate.Y) {
+		t.Errorf("estimate is NaN: %+v", estimate)
+	}
+}
+
+func TestParticleFilterFollowsMotorDrive(t *testing.T) {
+	e := NewEngineWithSeed(5)
+	c := e.Constants
+	c.Estimator = models.EstimatorParticleFilter
+	c.SlippageAmount = 0
+	motor := *c.Motor
+	motor.Enabled = true
+	c.Motor = &motor
+	e.UpdateConstants(c)
+
+	// Under voltage drive the wheel command stays zero, so only the measured
+	// wheel velocities can move the particles
+	if err := e.SetMotorCommand(models.MotorCommand{Left: 6, Right: 6}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 240; i++ {
+		e.Step(1.0 / 120)
+	}
+
+	if e.GroundTruth.X < 0.2 {
+		t.Fatalf("robot only reached x = %g under motor drive", e.GroundTruth.X)
+	}
+	estimate := e.Estimate()
+	if d := math.Hypot(estimate.X-e.GroundTruth.X, estimate.Y-e.GroundTruth.Y); d > 0.1 {
+		t.Errorf("estimate (%g, %g) is %g m from the robot at (%g, %g)",
+			estimate.X, estimate.Y, d, e.GroundTruth.X, e.GroundTruth.Y)
+	}
+}
@@ -0,0 +1,122 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// splineSamples is the number of polyline segments per spline span
+const splineSamples = 16
+
+// path is a polyline parameterized by arc length
+type path struct {
+	points []models.Point
+	cum    []float64 // Arc length from the start to each point
+}
+
+// pathProjection is the point of a path closest to a query point
+type pathProjection struct {
+	segment    int     // Index of the segment the point lies on
+	s          float64 // Arc length of the point
+	point      models.Point
+	heading    float64 // Direction of the path at the point
+	crossTrack float64 // Signed distance from the path, positive to its left
+}
+
+// newPath builds a path through points, dropping repeated points
+func newPath(points []models.Point) *path {
+	p := &path{}
+	for _, pt := range points {
+		if n := len(p.points); n > 0 {
+			last := p.points[n-1]
+			d := math.Hypot(pt.X-last.X, pt.Y-last.Y)
+			if d < 1e-9 {
+				continue
+			}
+			p.points = append(p.points, pt)
+			p.cum = append(p.cum, p.cum[n-1]+d)
+			continue
+		}
+		p.points = append(p.points, pt)
+		p.cum = append(p.cum, 0)
+	}
+	return p
+}
+
+// length returns the total arc length
+func (p *path) length() float64 {
+	return p.cum[len(p.cum)-1]
+}
+
+// project finds the closest point to q on the segments from segment from up
+// to arc length limit. Searching forward from the last projection keeps the
+// progress monotonic on paths that cross themselves.
+func (p *path) project(q models.Point, from int, limit float64) pathProjection {
+	best := pathProjection{crossTrack: math.Inf(1)}
+	bestDist := math.Inf(1)
+
+	for i := from; i < len(p.points)-1; i++ {
+		a, b := p.points[i], p.points[i+1]
+		dx, dy := b.X-a.X, b.Y-a.Y
+		segLen := p.cum[i+1] - p.cum[i]
+		t := math.Max(0, math.Min(((q.X-a.X)*dx+(q.Y-a.Y)*dy)/(segLen*segLen), 1))
+		closest := models.Point{X: a.X + t*dx, Y: a.Y + t*dy}
+
+		if d := math.Hypot(q.X-closest.X, q.Y-closest.Y); d < bestDist {
+			bestDist = d
+			cross := (dx*(q.Y-a.Y) - dy*(q.X-a.X)) / segLen
+			best = pathProjection{
+				segment:    i,
+				s:          p.cum[i] + t*segLen,
+				point:      closest,
+				heading:    math.Atan2(dy, dx),
+				crossTrack: math.Copysign(d, cross),
+			}
+		}
+		if p.cum[i+1] > limit {
+			break
+		}
+	}
+	return best
+}
+
+// pointAt returns the point at arc length s, clamped to the ends
+func (p *path) pointAt(s float64) models.Point {
+	if s <= 0 {
+		return p.points[0]
+	}
+	for i := 1; i < len(p.points); i++ {
+		if p.cum[i] >= s {
+			a, b := p.points[i-1], p.points[i]
+			t := (s - p.cum[i-1]) / (p.cum[i] - p.cum[i-1])
+			return models.Point{X: a.X + t*(b.X-a.X), Y: a.Y + t*(b.Y-a.Y)}
+		}
+	}
+	return p.points[len(p.points)-1]
+}
+
+// catmullRom samples a uniform Catmull-Rom spline that passes through every
+// point, with the end points repeated so the curve reaches them
+func catmullRom(points []models.Point) []models.Point {
+	if len(points) < 3 {
+		return points
+	}
+
+	at := func(i int) models.Point {
+		return points[max(0, min(i, len(points)-1))]
+	}
+	out := make([]models.Point, 0, (len(points)-1)*splineSamples+1)
+	for i := 0; i < len(points)-1; i++ {
+		p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+		for k := 0; k < splineSamples; k++ {
+			t := float64(k) / splineSamples
+			t2, t3 := t*t, t*t*t
+			out = append(out, models.Point{
+				X: 0.5 * (2*p1.X + (p2.X-p0.X)*t + (2*p0.X-5*p1.X+4*p2.X-p3.X)*t2 + (3*p1.X-p0.X-3*p2.X+p3.X)*t3),
+				Y: 0.5 * (2*p1.Y + (p2.Y-p0.Y)*t + (2*p0.Y-5*p1.Y+4*p2.Y-p3.Y)*t2 + (3*p1.Y-p0.Y-3*p2.Y+p3.Y)*t3),
+			})
+		}
+	}
+	return append(out, points[len(points)-1])
+}
@@ -0,0 +1,74 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// pidController is the discrete velocity loop of one wheel
+type pidController struct {
+	integral  float64 // Accumulated integral term (V)
+	prevError float64
+	primed    bool // Whether prevError holds the previous step's error
+}
+
+// update runs one step of the loop and returns its output before
+// saturation. The integral stops growing while the output is saturated in
+// the direction of the error, and is clamped to the configured limit.
+func (p *pidController) update(c models.PIDConfig, setpoint, measured, saturation, dt float64) float64 {
+	err := setpoint - measured
+	derivative := 0.0
+	if p.primed {
+		derivative = (err - p.prevError) / dt
+	}
+	p.prevError, p.primed = err, true
+
+	base := c.FeedForward*setpoint + c.Kp*err + c.Kd*derivative
+	step := c.Ki * err * dt
+	if output := base + p.integral + step; math.Abs(output) < saturation || (output > 0) != (err > 0) {
+		p.integral += step
+	}
+	if c.IntegralLimit > 0 {
+		p.integral = math.Max(-c.IntegralLimit, math.Min(p.integral, c.IntegralLimit))
+	}
+	return base + p.integral
+}
+
+// pidConfig returns the wheel velocity loop when it is enabled, or nil
+func (e *Engine) pidConfig() *models.PIDConfig {
+	if c := e.Constants.PID; c != nil && c.Enabled {
+		return c
+	}
+	return nil
+}
+
+// runPID returns the motor voltages the wheel velocity loops command for the
+// current wheel setpoints. The loops measure wheel velocity with the encoders,
+// as of the previous step.
+func (e *Engine) runPID(c models.PIDConfig, m models.MotorConfig, dt float64) (left, right float64) {
+	state := &models.WheelPIDState{
+		Left: models.PIDState{
+			Setpoint: e.WheelCommand.LeftVelocity,
+			Measured: e.Odometry.LeftWheel.Velocity,
+		},
+		Right: models.PIDState{
+			Setpoint: e.WheelCommand.RightVelocity,
+			Measured: e.Odometry.RightWheel.Velocity,
+		},
+	}
+
+	left = e.pid[0].update(c, state.Left.Setpoint, state.Left.Measured, m.SupplyVoltage, dt)
+	right = e.pid[1].update(c, state.Right.Setpoint, state.Right.Measured, m.SupplyVoltage, dt)
+	state.Left.Effort = math.Max(-m.SupplyVoltage, math.Min(left, m.SupplyVoltage))
+	state.Right.Effort = math.Max(-m.SupplyVoltage, math.Min(right, m.SupplyVoltage))
+
+	e.pidState = state
+	return state.Left.Effort, state.Right.Effort
+}
+
+// PIDState returns the wheel velocity loops of the last step, or nil when
+// they did not run
+func (e *Engine) PIDState() *models.WheelPIDState {
+	return e.pidState
+}
@@ -0,0 +1,76 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+func TestPIDTracksWheelSetpoints(t *testing.T) {
+	e := NewEngineWithSeed(1)
+	constants := models.DefaultRobotConstants()
+	constants.Motor.Enabled = true
+	// Without feed-forward the integral has to supply the steady-state voltage
+	constants.PID.FeedForward = 0
+	e.UpdateConstants(constants)
+
+	e.SetWheelCommand(models.WheelCommand{LeftVelocity: 10, RightVelocity: -5})
+	for i := 0; i < 500; i++ {
+		e.Step(0.01)
+	}
+	if v := e.GroundTruth.LeftWheel.Velocity; math.Abs(v-10) > 0.1 {
+		t.Errorf("left wheel at %g rad/s, want 10", v)
+	}
+	if v := e.GroundTruth.RightWheel.Velocity; math.Abs(v+5) > 0.1 {
+		t.Errorf("right wheel at %g rad/s, want -5", v)
+	}
+
+	state := e.PIDState()
+	if state == nil {
+		t.Fatal("no PID state while the loops run")
+	}
+	if state.Left.Setpoint != 10 || state.Right.Setpoint != -5 {
+		t.Errorf("setpoints = %g and %g, want 10 and -5", state.Left.Setpoint, state.Right.Setpoint)
+	}
+
+	// A motor command bypasses the loops
+	if err := e.SetMotorCommand(models.MotorCommand{}); err != nil {
+		t.Fatalf("SetMotorCommand: %v", err)
+	}
+	e.Step(0.01)
+	if state := e.PIDState(); state != nil {
+		t.Errorf("PID state = %+v under a motor command, want nil", state)
+	}
+}
+
+func TestPIDIntegralDoesNotWindUp(t *testing.T) {
+	c := models.PIDConfig{Enabled: true, Kp: 1, Ki: 10}
+	const saturation, dt = 12, 0.01
+
+	// An unreachable setpoint saturates the output, which freezes the integral
+	var p pidController
+	for i := 0; i < 1000; i++ {
+		if output := p.update(c, 100, 0, saturation, dt); output < saturation {
+			t.Fatalf("step %d: output %g below saturation", i, output)
+		}
+	}
+	if p.integral != 0 {
+		t.Errorf("integral = %g after saturating from the first step, want 0", p.integral)
+	}
+
+	// Below saturation the integral grows until the clamp holds it
+	c.IntegralLimit = 2
+	p = pidController{}
+	for i := 0; i < 1000; i++ {
+		p.update(c, 1, 0, saturation, dt)
+	}
+	if p.integral != c.IntegralLimit {
+		t.Errorf("integral = %g, want the limit %g", p.integral, c.IntegralLimit)
+	}
+
+	// The clamp keeps the loop responsive when the error reverses
+	if output := p.update(c, -1, 0, saturation, dt); output >= 1 {
+		t.Errorf("output = %g after the error reversed, want below 1", output)
+	}
+}
@@ -0,0 +1,161 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// purePursuit follows a path by steering along the arc that meets the path a
+// lookahead distance ahead of the closest point
+type purePursuit struct {
+	path          *path
+	feedback      string
+	lookahead     float64
+	speed         float64
+	goalTolerance float64
+	timeout       float64
+
+	last      pathProjection // Last projection of the feedback pose
+	lastTruth pathProjection // Last projection of the true pose
+	tracking  *models.TrackingError
+
+	started      float64 // Simulated time of the first update, -1 before it
+	nextProgress float64
+	best         float64 // Furthest arc length reached
+	bestTime     float64 // Simulated time best was last improved
+}
+
+// NewPurePursuit creates the path following controller for a followPath
+// request
+func NewPurePursuit(req models.FollowPathPayload) (Controller, error) {
+	points := req.Points
+	if req.Spline {
+		points = catmullRom(points)
+	}
+	p := newPath(points)
+	if len(p.points) < 2 {
+		return nil, errors.New("followPath needs at least two distinct points")
+	}
+
+	c := &purePursuit{
+		path:          p,
+		feedback:      req.Feedback,
+		lookahead:     req.Lookahead,
+		speed:         req.Speed,
+		goalTolerance: req.GoalTolerance,
+		timeout:       req.Timeout,
+		started:       -1,
+	}
+	if c.feedback == "" {
+		c.feedback = models.FeedbackOdometry
+	}
+	if c.lookahead <= 0 {
+		c.lookahead = 0.3
+	}
+	if c.speed <= 0 {
+		c.speed = 0.3
+	}
+	if c.goalTolerance <= 0 {
+		c.goalTolerance = 0.05
+	}
+	if c.timeout <= 0 {
+		c.timeout = 120
+	}
+
+	switch c.feedback {
+	case models.FeedbackGroundTruth, models.FeedbackOdometry, models.FeedbackEstimate:
+	default:
+		return nil, fmt.Errorf("unknown feedback source %q", c.feedback)
+	}
+	return c, nil
+}
+
+// Name implements Controller
+func (c *purePursuit) Name() string {
+	return models.MsgTypeFollowPath
+}
+
+// Feedback implements Controller
+func (c *purePursuit) Feedback() string {
+	return c.feedback
+}
+
+// Tracking implements TrackingSource
+func (c *purePursuit) Tracking() *models.TrackingError {
+	return c.tracking
+}
+
+// Update implements Controller
+func (c *purePursuit) Update(in ControllerInput) (models.TwistCommand, bool) {
+	if c.started < 0 {
+		c.started = in.SimTime
+		c.best, c.bestTime = math.Inf(-1), in.SimTime
+	}
+
+	// Only look a little past the lookahead for the closest point, so the
+	// progress along the path cannot jump ahead where the path loops back
+	window := 2*c.lookahead + c.speed
+	pose := models.Point{X: in.Pose.X, Y: in.Pose.Y}
+	fb := c.path.project(pose, c.last.segment, c.last.s+window)
+	gt := c.path.project(models.Point{X: in.GroundTruth.X, Y: in.GroundTruth.Y}, c.lastTruth.segment, c.lastTruth.s+window)
+	c.last, c.lastTruth = fb, gt
+
+	c.tracking = &models.TrackingError{
+		CrossTrack:         gt.crossTrack,
+		HeadingError:       angleDiff(in.GroundTruth.Theta, gt.heading),
+		FeedbackCrossTrack: fb.crossTrack,
+	}
+
+	end := c.path.points[len(c.path.points)-1]
+	endDist := math.Hypot(end.X-pose.X, end.Y-pose.Y)
+	remaining := c.path.length() - fb.s
+
+	event := models.NavigationEvent{
+		Controller:       c.Name(),
+		Feedback:         c.feedback,
+		Waypoint:         fb.segment,
+		Waypoints:        len(c.path.points) - 1,
+		Distance:         remaining,
+		HeadingError:     angleDiff(in.Pose.Theta, fb.heading),
+		GroundTruthError: math.Hypot(end.X-in.GroundTruth.X, end.Y-in.GroundTruth.Y),
+		SimTime:          in.SimTime,
+	}
+
+	switch {
+	case remaining <= c.lookahead && endDist <= c.goalTolerance:
+		event.Event = models.NavigationArrived
+	case in.SimTime-c.started > c.timeout:
+		event.Event, event.Reason = models.NavigationFailed, "timeout"
+	case fb.s > c.best+navStallProgress:
+		c.best, c.bestTime = fb.s, in.SimTime
+	case in.SimTime-c.bestTime > navStallTime:
+		event.Event, event.Reason = models.NavigationFailed, "stalled"
+	}
+	if event.Event != "" {
+		in.Emit(event)
+		return models.TwistCommand{}, true
+	}
+
+	if in.SimTime >= c.nextProgress {
+		c.nextProgress = in.SimTime + navProgressInterval
+		event.Event = models.NavigationProgress
+		in.Emit(event)
+	}
+
+	// Curvature of the arc through the lookahead point: κ = 2y / d², with y
+	// the lateral offset of the point in the robot frame
+	target := c.path.pointAt(fb.s + c.lookahead)
+	dx, dy := target.X-pose.X, target.Y-pose.Y
+	lateral := -math.Sin(in.Pose.Theta)*dx + math.Cos(in.Pose.Theta)*dy
+	curvature := 0.0
+	if d2 := dx*dx + dy*dy; d2 > 1e-12 {
+		curvature = 2 * lateral / d2
+	}
+
+	// Slow down over the last stretch so the robot stops at the end
+	v := math.Min(c.speed, endDist)
+	return models.TwistCommand{LinearVel: v, AngularVel: v * curvature}, false
+}
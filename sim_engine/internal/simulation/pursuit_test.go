@@ -0,0 +1,67 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+func TestPurePursuitFollowsPath(t *testing.T) {
+	for _, spline := range []bool{false, true} {
+		name := "polyline"
+		if spline {
+			name = "spline"
+		}
+		t.Run(name, func(t *testing.T) {
+			e := controllerEngine(t)
+			points := []models.Point{{X: 0, Y: 0}, {X: 1.5, Y: 0}, {X: 1.5, Y: 1.5}, {X: 0, Y: 1.5}}
+			c, err := NewPurePursuit(models.FollowPathPayload{
+				Points:   points,
+				Spline:   spline,
+				Feedback: models.FeedbackGroundTruth,
+			})
+			if err != nil {
+				t.Fatalf("NewPurePursuit: %v", err)
+			}
+			if err := e.SetController(c); err != nil {
+				t.Fatalf("SetController: %v", err)
+			}
+
+			// Track the worst cross-track error on the way
+			worst := 0.0
+			const dt = 1.0 / 120
+			var event *models.NavigationEvent
+			for step := 0; step < 60*120 && event == nil; step++ {
+				e.Step(dt)
+				if tracking := e.Tracking(); tracking != nil {
+					worst = math.Max(worst, math.Abs(tracking.CrossTrack))
+				}
+				for _, msg := range e.DrainEvents() {
+					if nav, ok := msg.Payload.(models.NavigationEvent); ok &&
+						(nav.Event == models.NavigationArrived || nav.Event == models.NavigationFailed) {
+						event = &nav
+					}
+				}
+			}
+
+			if event == nil || event.Event != models.NavigationArrived {
+				t.Fatalf("path following ended with %+v", event)
+			}
+			end := points[len(points)-1]
+			if d := math.Hypot(e.GroundTruth.X-end.X, e.GroundTruth.Y-end.Y); d > 0.1 {
+				t.Errorf("stopped %g m from the end of the path", d)
+			}
+			// Corners are cut by up to about the lookahead
+			if worst > 0.3 {
+				t.Errorf("worst cross-track error %g m", worst)
+			}
+		})
+	}
+}
+
+func TestPurePursuitRejectsShortPath(t *testing.T) {
+	if _, err := NewPurePursuit(models.FollowPathPayload{Points: []models.Point{{X: 1}}}); err == nil {
+		t.Error("NewPurePursuit accepted a single point")
+	}
+}
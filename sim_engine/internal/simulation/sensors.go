@@ -0,0 +1,232 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// timeEpsilon absorbs floating point drift in the accumulated simulation time
+// when comparing it against sample and outage times
+const timeEpsilon = 1e-9
+
+// gnssSensor samples noisy absolute positions from ground truth
+type gnssSensor struct {
+	rand       *rand.Rand
+	nextSample float64 // Simulated time of the next fix
+}
+
+// sample returns a fix when one is due at simTime and no outage is in
+// progress, or nil
+func (s *gnssSensor) sample(config *models.GNSSConfig, gt models.RobotState, simTime float64) *models.GNSSReading {
+	if config == nil || !config.Enabled || !due(&s.nextSample, config.Rate, simTime) {
+		return nil
+	}
+	for _, outage := range config.Outages {
+		if outage.Contains(simTime + timeEpsilon) {
+			return nil
+		}
+	}
+
+	return &models.GNSSReading{
+		X:        gt.X + s.rand.NormFloat64()*config.NoiseStd,
+		Y:        gt.Y + s.rand.NormFloat64()*config.NoiseStd,
+		NoiseStd: config.NoiseStd,
+		SimTime:  simTime,
+	}
+}
+
+// imuSensor samples yaw rate and forward acceleration from ground truth. The
+// biases wander every step, whether or not a reading is due.
+type imuSensor struct {
+	rand       *rand.Rand
+	nextSample float64 // Simulated time of the next reading
+	gyroBias   float64
+	accelBias  float64
+	lastVel    float64 // Ground truth speed at the previous reading
+	lastTime   float64 // Simulated time of the previous reading
+}
+
+// sample returns a reading when one is due at simTime, or nil
+func (s *imuSensor) sample(config *models.IMUConfig, gt models.RobotState, simTime, dt float64) *models.IMUReading {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+
+	s.gyroBias += s.rand.NormFloat64() * config.GyroBiasWalk * math.Sqrt(dt)
+	s.accelBias += s.rand.NormFloat64() * config.AccelBiasWalk * math.Sqrt(dt)
+
+	if !due(&s.nextSample, config.Rate, simTime) {
+		return nil
+	}
+
+	// Acceleration is averaged over the interval since the previous reading,
+	// as an accelerometer's low-pass filter would
+	accel := 0.0
+	if elapsed := simTime - s.lastTime; elapsed > 0 {
+		accel = (gt.LinearVel - s.lastVel) / elapsed
+	}
+	s.lastVel, s.lastTime = gt.LinearVel, simTime
+
+	yawRate := gt.AngularVel + s.gyroBias + s.rand.NormFloat64()*config.GyroNoiseStd
+	accel += s.accelBias + s.rand.NormFloat64()*config.AccelNoiseStd
+
+	return &models.IMUReading{
+		YawRate:      quantize(yawRate, config.GyroResolution),
+		ForwardAccel: quantize(accel, config.AccelResolution),
+		GyroNoiseStd: config.GyroNoiseStd,
+		SimTime:      simTime,
+	}
+}
+
+// due reports whether a sensor sampling at rate (per second) takes a reading
+// at simTime, and advances *next to the following sample time. The period is
+// kept fixed so the average rate holds when it is not a multiple of the step
+// rate.
+func due(next *float64, rate, simTime float64) bool {
+	if rate <= 0 || simTime+timeEpsilon < *next {
+		return false
+	}
+	*next += 1 / rate
+	if *next <= simTime {
+		*next = simTime + 1/rate
+	}
+	return true
+}
+
+// quantize rounds v to a multiple of step; a step of 0 leaves v unchanged
+func quantize(v, step float64) float64 {
+	if step <= 0 {
+		return v
+	}
+	return math.Round(v/step) * step
+}
+
+// encoderSensor counts wheel ticks from the ground truth wheel rotation
+type encoderSensor struct {
+	rand        *rand.Rand
+	left        encoderChannel
+	right       encoderChannel
+	ticksPerRev int
+	simTime     float64
+}
+
+// encoderChannel is the tick counter of one wheel
+type encoderChannel struct {
+	count int64 // Ticks counted so far
+	edges int64 // Tick edges the wheel has actually passed at the last latch
+}
+
+// sample latches both counters for this step and returns the tick deltas
+// since the previous step
+func (s *encoderSensor) sample(config models.EncoderConfig, left, right models.WheelState, simTime float64) (dLeft, dRight int64) {
+	if config.TicksPerRev <= 0 {
+		return 0, 0
+	}
+	if config.TicksPerRev != s.ticksPerRev {
+		// The counters restart at the current rotation when the resolution changes
+		s.ticksPerRev = config.TicksPerRev
+		s.left.edges = s.edgesAt(left.Rotation)
+		s.right.edges = s.edgesAt(right.Rotation)
+	}
+	s.simTime = simTime
+
+	return s.count(&s.left, config, left), s.count(&s.right, config, right)
+}
+
+// count latches one channel, returning the ticks it counted this step
+func (s *encoderSensor) count(ch *encoderChannel, config models.EncoderConfig, wheel models.WheelState) int64 {
+	// A latch that fires early or late sees the wheel a little further along
+	rotation := wheel.Rotation + wheel.Velocity*s.rand.NormFloat64()*config.SampleJitter
+
+	edges := s.edgesAt(rotation)
+	delta := edges - ch.edges
+	ch.edges = edges
+
+	counted := delta
+	if config.MissedTickProb > 0 {
+		step := int64(1)
+		if delta < 0 {
+			step = -1
+		}
+		for i := delta; i != 0; i -= step {
+			if s.rand.Float64() < config.MissedTickProb {
+				counted -= step
+			}
+		}
+	}
+	ch.count += counted
+	return counted
+}
+
+// edgesAt returns the number of tick edges in rotation radians of travel
+func (s *encoderSensor) edgesAt(rotation float64) int64 {
+	return int64(math.Floor(rotation * float64(s.ticksPerRev) / (2 * math.Pi)))
+}
+
+// reading returns the latched counters
+func (s *encoderSensor) reading(ticksPerRev int) *models.EncoderReading {
+	return &models.EncoderReading{
+		LeftTicks:   s.left.count,
+		RightTicks:  s.right.count,
+		TicksPerRev: ticksPerRev,
+		SimTime:     s.simTime,
+	}
+}
+
+// lidarSensor ray-casts planar range scans from ground truth
+type lidarSensor struct {
+	rand  *rand.Rand
+	steps int // Steps since the last scan
+}
+
+// sample returns a scan when one is due this step, or nil
+func (s *lidarSensor) sample(config *models.LidarConfig, m RangeMap, gt models.RobotState, simTime, dt float64) *models.LaserScan {
+	if config == nil || !config.Enabled || config.Beams <= 0 || m == nil {
+		return nil
+	}
+
+	s.steps++
+	if s.steps < config.UpdateEvery {
+		return nil
+	}
+	s.steps = 0
+
+	scanTime := float64(max(config.UpdateEvery, 1)) * dt
+	scan := &models.LaserScan{
+		AngleMin:      -config.FOV / 2,
+		TimeIncrement: scanTime / float64(config.Beams),
+		ScanTime:      scanTime,
+		RangeMin:      config.MinRange,
+		RangeMax:      config.MaxRange,
+		Ranges:        make([]float64, config.Beams),
+		SimTime:       simTime,
+	}
+	// A full circle ends where it starts, so its last beam stops one step
+	// short of the first
+	switch {
+	case config.FOV >= 2*math.Pi-1e-9:
+		scan.AngleIncrement = config.FOV / float64(config.Beams)
+	case config.Beams > 1:
+		scan.AngleIncrement = config.FOV / float64(config.Beams-1)
+	}
+	scan.AngleMax = scan.AngleMin + float64(config.Beams-1)*scan.AngleIncrement
+
+	for i := range scan.Ranges {
+		if s.rand.Float64() < config.DropoutProb {
+			continue
+		}
+
+		angle := gt.Theta + scan.AngleMin + float64(i)*scan.AngleIncrement
+		r := m.Raycast(gt.X, gt.Y, angle, config.MaxRange)
+		if r < config.MaxRange {
+			r += s.rand.NormFloat64() * config.NoiseStd
+		}
+		// Like a dropout, a beam with no return in range reads 0
+		if r >= config.MinRange && r < config.MaxRange {
+			scan.Ranges[i] = r
+		}
+	}
+	return scan
+}
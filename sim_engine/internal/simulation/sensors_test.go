@@ -0,0 +1,302 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+func TestLidarScansOnSchedule(t *testing.T) {
+	w := testWorld(t)
+	config := models.DefaultLidarConfig()
+	config.NoiseStd = 0
+	config.DropoutProb = 0
+	config.UpdateEvery = 3
+	s := lidarSensor{rand: rand.New(rand.NewSource(1))}
+	gt := models.RobotState{X: -1, Y: 0.5, Theta: 0.2}
+
+	var scans int
+	var scan *models.LaserScan
+	for i := 0; i < 9; i++ {
+		if got := s.sample(&config, w, gt, 0, 0.01); got != nil {
+			scans++
+			scan = got
+		}
+	}
+	if scans != 3 {
+		t.Fatalf("%d scans in 9 steps, want 3", scans)
+	}
+
+	if len(scan.Ranges) != config.Beams {
+		t.Fatalf("%d ranges, want %d", len(scan.Ranges), config.Beams)
+	}
+	if math.Abs(scan.AngleMax-scan.AngleMin-config.FOV) > 1e-12 {
+		t.Errorf("scan covers %g rad, want %g", scan.AngleMax-scan.AngleMin, config.FOV)
+	}
+	if want := scan.ScanTime / float64(config.Beams); math.Abs(scan.TimeIncrement-want) > 1e-15 {
+		t.Errorf("time increment = %g, want %g", scan.TimeIncrement, want)
+	}
+	for i, r := range scan.Ranges {
+		angle := gt.Theta + scan.AngleMin + float64(i)*scan.AngleIncrement
+		want := w.Raycast(gt.X, gt.Y, angle, config.MaxRange)
+		if want < config.MinRange || want >= config.MaxRange {
+			want = 0
+		}
+		if math.Abs(r-want) > 1e-12 {
+			t.Errorf("beam %d = %g, want %g", i, r, want)
+		}
+	}
+}
+
+func TestLidarDisabled(t *testing.T) {
+	config := models.DefaultLidarConfig()
+	config.Enabled = false
+	config.UpdateEvery = 1
+	s := lidarSensor{rand: rand.New(rand.NewSource(1))}
+	if scan := s.sample(&config, testWorld(t), models.RobotState{}, 0, 0.01); scan != nil {
+		t.Error("a disabled lidar produced a scan")
+	}
+}
+
+func TestLidarFullCircleDoesNotRepeatBeams(t *testing.T) {
+	config := models.DefaultLidarConfig()
+	config.FOV = 2 * math.Pi
+	config.Beams = 8
+	config.UpdateEvery = 1
+	s := lidarSensor{rand: rand.New(rand.NewSource(1))}
+	scan := s.sample(&config, testWorld(t), models.RobotState{}, 0, 0.01)
+
+	if want := math.Pi / 4; math.Abs(scan.AngleIncrement-want) > 1e-12 {
+		t.Errorf("angle increment = %g, want %g", scan.AngleIncrement, want)
+	}
+	if gap := scan.AngleMin + 2*math.Pi - scan.AngleMax; math.Abs(gap-scan.AngleIncrement) > 1e-12 {
+		t.Errorf("last beam is %g rad from the first, want %g", gap, scan.AngleIncrement)
+	}
+}
+
+func TestLidarReportsOutOfRangeAsInvalid(t *testing.T) {
+	w := testWorld(t)
+	config := models.DefaultLidarConfig()
+	config.NoiseStd = 0
+	config.DropoutProb = 0
+	config.UpdateEvery = 1
+	config.FOV = 2 * math.Pi
+	config.Beams = 72
+	config.MinRange = 0.3
+	config.MaxRange = 1
+	s := lidarSensor{rand: rand.New(rand.NewSource(1))}
+
+	// Next to the circle at (1.5, 0.5) r0.3, with the walls beyond range
+	scan := s.sample(&config, w, models.RobotState{X: 1.05, Y: 0.5}, 0, 0.01)
+	var near, far int
+	for i, r := range scan.Ranges {
+		angle := scan.AngleMin + float64(i)*scan.AngleIncrement
+		truth := w.Raycast(1.05, 0.5, angle, config.MaxRange)
+		switch {
+		case truth < config.MinRange:
+			near++
+		case truth >= config.MaxRange:
+			far++
+		default:
+			continue
+		}
+		if r != 0 {
+			t.Errorf("beam %d at %g m reads %g, want 0", i, truth, r)
+		}
+	}
+	if near == 0 || far == 0 {
+		t.Fatalf("scan has %d beams below range and %d beyond it; want some of each", near, far)
+	}
+}
+
+func TestIMUGyroBiasWalks(t *testing.T) {
+	config := models.DefaultIMUConfig()
+	config.GyroNoiseStd = 0
+	config.GyroResolution = 0
+	config.GyroBiasWalk = 0.01
+
+	// With no white noise a stationary gyro reads its bias, which should
+	// spread as walk·√t across independent sensors
+	const sensors, dt, duration = 200, 0.01, 25.0
+	sumSq := 0.0
+	for seed := int64(0); seed < sensors; seed++ {
+		s := imuSensor{rand: rand.New(rand.NewSource(seed))}
+		var last *models.IMUReading
+		for i := 1; i <= int(duration/dt); i++ {
+			if reading := s.sample(&config, models.RobotState{}, float64(i)*dt, dt); reading != nil {
+				last = reading
+			}
+		}
+		sumSq += last.YawRate * last.YawRate
+	}
+	std := math.Sqrt(sumSq / sensors)
+	if want := config.GyroBiasWalk * math.Sqrt(duration); math.Abs(std-want) > 0.2*want {
+		t.Errorf("bias spread after %gs = %g rad/s, want about %g", duration, std, want)
+	}
+}
+
+func TestIMUNoiseAndQuantization(t *testing.T) {
+	config := models.DefaultIMUConfig()
+	config.GyroBiasWalk = 0
+	config.GyroNoiseStd = 0.05
+	config.GyroResolution = 0.01
+	config.AccelBiasWalk = 0
+	config.AccelResolution = 0.005
+	s := imuSensor{rand: rand.New(rand.NewSource(9))}
+	gt := models.RobotState{AngularVel: 0.3}
+
+	const dt = 0.01
+	var readings []float64
+	for i := 1; i <= 5000; i++ {
+		reading := s.sample(&config, gt, float64(i)*dt, dt)
+		if reading == nil {
+			continue
+		}
+		for _, q := range []struct{ v, step float64 }{
+			{reading.YawRate, config.GyroResolution},
+			{reading.ForwardAccel, config.AccelResolution},
+		} {
+			if n := q.v / q.step; math.Abs(n-math.Round(n)) > 1e-6 {
+				t.Fatalf("reading %g is not a multiple of %g", q.v, q.step)
+			}
+		}
+		readings = append(readings, reading.YawRate)
+	}
+	if want := int(5000 * dt * config.Rate); len(readings) != want {
+		t.Errorf("%d readings in %gs, want %d", len(readings), 5000*dt, want)
+	}
+
+	mean, sumSq := 0.0, 0.0
+	for _, r := range readings {
+		mean += r
+	}
+	mean /= float64(len(readings))
+	for _, r := range readings {
+		sumSq += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(sumSq / float64(len(readings)))
+	if math.Abs(mean-gt.AngularVel) > 0.01 {
+		t.Errorf("mean yaw rate = %g, want %g", mean, gt.AngularVel)
+	}
+	if math.Abs(std-config.GyroNoiseStd) > 0.1*config.GyroNoiseStd {
+		t.Errorf("yaw rate noise = %g, want about %g", std, config.GyroNoiseStd)
+	}
+}
+
+func TestGNSSPublishesAtRateOutsideOutages(t *testing.T) {
+	config := models.DefaultGNSSConfig()
+	config.Rate = 5
+	config.Outages = []models.OutageWindow{{Start: 2, End: 4}}
+	s := gnssSensor{rand: rand.New(rand.NewSource(1))}
+
+	const dt = 0.01
+	var times []float64
+	for i := 0; i < 600; i++ {
+		simTime := float64(i) * dt
+		if fix := s.sample(&config, models.RobotState{}, simTime); fix != nil {
+			times = append(times, fix.SimTime)
+		}
+	}
+
+	var before, after int
+	for _, simTime := range times {
+		switch {
+		case config.Outages[0].Contains(simTime):
+			t.Errorf("fix published at %gs, inside the outage", simTime)
+		case simTime < 2:
+			before++
+		default:
+			after++
+		}
+	}
+	// 5 Hz over the 2 s before the outage and the 2 s after it
+	if before != 10 || after != 10 {
+		t.Errorf("%d fixes before the outage and %d after, want 10 and 10", before, after)
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i] - times[i-1]; gap < 1/config.Rate-1e-9 {
+			t.Errorf("fixes at %gs and %gs are closer than the %g Hz period", times[i-1], times[i], config.Rate)
+		}
+	}
+}
+
+func TestGNSSDisabled(t *testing.T) {
+	config := models.DefaultGNSSConfig()
+	config.Enabled = false
+	s := gnssSensor{rand: rand.New(rand.NewSource(1))}
+	for i := 1; i <= 200; i++ {
+		if fix := s.sample(&config, models.RobotState{}, float64(i)*0.01); fix != nil {
+			t.Fatalf("a disabled GNSS published a fix at %gs", fix.SimTime)
+		}
+	}
+}
+
+// turnEncoder turns both wheels from rotation from to to in steps of step
+// radians and returns the ticks counted by each
+func turnEncoder(s *encoderSensor, config models.EncoderConfig, from, to, step float64) (left, right int64) {
+	steps := int(math.Round(math.Abs(to-from) / step))
+	for i := 1; i <= steps; i++ {
+		wheel := models.WheelState{Rotation: from + (to-from)*float64(i)/float64(steps)}
+		dLeft, dRight := s.sample(config, wheel, wheel, float64(i)*0.01)
+		left += dLeft
+		right += dRight
+	}
+	return left, right
+}
+
+func TestEncoderCountsTicks(t *testing.T) {
+	config := models.EncoderConfig{TicksPerRev: 100}
+	s := encoderSensor{rand: rand.New(rand.NewSource(1))}
+	s.sample(config, models.WheelState{}, models.WheelState{}, 0)
+
+	// Stop halfway between ticks so rounding cannot add or drop one
+	tick := 2 * math.Pi / float64(config.TicksPerRev)
+	left, right := turnEncoder(&s, config, 0, 350.5*tick, 0.01)
+	if left != 350 || right != 350 {
+		t.Errorf("counted %d and %d ticks in 3.5 turns, want 350", left, right)
+	}
+	back, _ := turnEncoder(&s, config, 350.5*tick, 200.5*tick, 0.01)
+	if back != -150 {
+		t.Errorf("counted %d ticks turning back 1.5 turns, want -150", back)
+	}
+	if reading := s.reading(config.TicksPerRev); reading.LeftTicks != 200 || reading.RightTicks != 200 {
+		t.Errorf("counters read %d and %d, want 200", reading.LeftTicks, reading.RightTicks)
+	}
+}
+
+func TestEncoderMissesTicks(t *testing.T) {
+	config := models.EncoderConfig{TicksPerRev: 1000, MissedTickProb: 0.1}
+	s := encoderSensor{rand: rand.New(rand.NewSource(2))}
+	s.sample(config, models.WheelState{}, models.WheelState{}, 0)
+
+	const turns = 20
+	left, _ := turnEncoder(&s, config, 0, turns*2*math.Pi, 0.05)
+	want := (1 - config.MissedTickProb) * turns * float64(config.TicksPerRev)
+	if math.Abs(float64(left)-want) > 0.02*want {
+		t.Errorf("counted %d ticks with %g of them missed, want about %g", left, config.MissedTickProb, want)
+	}
+}
+
+func TestOdometryFollowsEncoderTicks(t *testing.T) {
+	e := NewEngineWithSeed(4)
+	c := e.Constants
+	c.SlippageAmount = 0
+	e.UpdateConstants(c)
+	e.SetWheelCommand(models.WheelCommand{LeftVelocity: 10, RightVelocity: 10})
+	for i := 0; i < 240; i++ {
+		e.Step(1.0 / 120)
+	}
+
+	// Without slip, dead reckoning from the ticks tracks the robot to within
+	// the encoder resolution
+	encoders := e.Encoders()
+	radPerTick := 2 * math.Pi / float64(encoders.TicksPerRev)
+	if got := float64(encoders.LeftTicks) * radPerTick; math.Abs(got-e.GroundTruth.LeftWheel.Rotation) > 2*radPerTick {
+		t.Errorf("left counter reads %g rad, wheel turned %g rad", got, e.GroundTruth.LeftWheel.Rotation)
+	}
+	if d := math.Hypot(e.Odometry.X-e.GroundTruth.X, e.Odometry.Y-e.GroundTruth.Y); d > 2*radPerTick*c.WheelRadius {
+		t.Errorf("odometry is %g m from the robot after %g m of travel", d, e.GroundTruth.X)
+	}
+}
@@ -0,0 +1,67 @@
+package simulation
+
+import "github.com/amogh1216/robot-vis/sim_engine/internal/models"
+
+// watchdog stops a manually driven robot whose commands stop arriving, as
+// when the client freezes or loses its connection
+type watchdog struct {
+	timeout     float64 // Simulated seconds without a command before it trips, 0 when disabled
+	lastCommand float64 // SimTime of the last command
+	tripped     bool    // Whether it tripped since the last command
+}
+
+// SetCommandTimeout arms the watchdog: once timeout seconds of simulated time
+// pass without a wheel, twist or motor command, the wheels are commanded to
+// stop. A timeout of 0 disables it. Callers running faster or slower than
+// real time scale a wall-clock timeout to simulated time themselves.
+func (e *Engine) SetCommandTimeout(timeout float64) {
+	e.watchdog.timeout = max(timeout, 0)
+	e.feedWatchdog()
+}
+
+// RescaleCommandTimeout changes the watchdog timeout without counting as a
+// command, as when the simulation speed changes: the last command keeps its
+// age and a tripped watchdog stays tripped. A timeout of 0 disables it.
+func (e *Engine) RescaleCommandTimeout(timeout float64) {
+	e.watchdog.timeout = max(timeout, 0)
+}
+
+// CommandTimeout returns the watchdog timeout, 0 when it is disabled
+func (e *Engine) CommandTimeout() float64 {
+	return e.watchdog.timeout
+}
+
+// feedWatchdog records that a command arrived
+func (e *Engine) feedWatchdog() {
+	e.watchdog.lastCommand = e.SimTime
+	e.watchdog.tripped = false
+}
+
+// checkWatchdog stops the wheels when the last command is older than the
+// timeout. The wheels then ramp down under their usual acceleration limits.
+// An autonomous controller commands the robot itself and keeps it fed.
+func (e *Engine) checkWatchdog() {
+	w := &e.watchdog
+	if w.timeout <= 0 || w.tripped {
+		return
+	}
+	if e.controller != nil {
+		e.feedWatchdog()
+		return
+	}
+	if e.SimTime-w.lastCommand < w.timeout {
+		return
+	}
+	if e.WheelCommand == (models.WheelCommand{}) && e.MotorCommand == nil {
+		return
+	}
+
+	w.tripped = true
+	e.WheelCommand = models.WheelCommand{}
+	e.MotorCommand = nil
+	e.emit(models.MsgTypeWatchdogTripped, models.WatchdogPayload{
+		Timeout:     w.timeout,
+		LastCommand: w.lastCommand,
+		SimTime:     e.SimTime,
+	})
+}
@@ -0,0 +1,87 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// watchdogTrips counts the watchdog events among events
+func watchdogTrips(events []models.WSMessage) int {
+	n := 0
+	for _, event := range events {
+		if event.Type == models.MsgTypeWatchdogTripped {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWatchdogStopsRobotAfterTimeout(t *testing.T) {
+	e := NewEngineWithSeed(1)
+	e.SetCommandTimeout(0.5)
+	e.SetWheelCommand(models.WheelCommand{LeftVelocity: 2, RightVelocity: 2})
+
+	const dt = 0.01
+	for i := 0; i < 40; i++ {
+		e.Step(dt)
+	}
+	if n := watchdogTrips(e.DrainEvents()); n != 0 || e.WheelCommand == (models.WheelCommand{}) {
+		t.Fatalf("watchdog tripped before the timeout")
+	}
+
+	for i := 0; i < 20; i++ {
+		e.Step(dt)
+	}
+	if e.WheelCommand != (models.WheelCommand{}) {
+		t.Errorf("wheel command = %+v after the timeout, want stopped", e.WheelCommand)
+	}
+	if n := watchdogTrips(e.DrainEvents()); n != 1 {
+		t.Errorf("got %d watchdog events, want 1", n)
+	}
+}
+
+func TestWatchdogFedByCommands(t *testing.T) {
+	e := NewEngineWithSeed(1)
+	e.SetCommandTimeout(0.5)
+
+	const dt = 0.01
+	for i := 0; i < 200; i++ {
+		if i%30 == 0 {
+			e.SetWheelCommand(models.WheelCommand{LeftVelocity: 2, RightVelocity: 2})
+		}
+		e.Step(dt)
+	}
+	if n := watchdogTrips(e.DrainEvents()); n != 0 {
+		t.Errorf("watchdog tripped %d times while commands kept arriving", n)
+	}
+}
+
+func TestWatchdogRescaleDoesNotFeed(t *testing.T) {
+	e := NewEngineWithSeed(1)
+	e.SetCommandTimeout(0.5)
+	e.SetWheelCommand(models.WheelCommand{LeftVelocity: 2, RightVelocity: 2})
+
+	const dt = 0.01
+	for i := 0; i < 30; i++ {
+		e.Step(dt)
+	}
+	// The last command is already older than the shorter timeout
+	e.RescaleCommandTimeout(0.2)
+	e.Step(dt)
+	if n := watchdogTrips(e.DrainEvents()); n != 1 {
+		t.Fatalf("got %d watchdog events after shortening the timeout, want 1", n)
+	}
+
+	// Rescaling a tripped watchdog neither re-arms nor trips it again
+	e.RescaleCommandTimeout(1)
+	for i := 0; i < 200; i++ {
+		e.Step(dt)
+	}
+	if n := watchdogTrips(e.DrainEvents()); n != 0 {
+		t.Errorf("got %d more watchdog events after rescaling, want none", n)
+	}
+	if e.WheelCommand != (models.WheelCommand{}) {
+		t.Errorf("wheel command = %+v after rescaling, want stopped", e.WheelCommand)
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"log"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// flushSize is the number of buffered points written per transaction.
+// Writing on every step would cost an fsync per simulation tick.
+const flushSize = 120
+
+// writeQueue is the number of full batches and events that may wait for the
+// writer before Add blocks and LogEvent drops events
+const writeQueue = 8
+
+// record is a write queued for the writer: a batch of points or an event
+type record struct {
+	points []models.TrajectoryPoint
+	event  *models.SessionEvent
+}
+
+// Recorder buffers the trajectory of a running session and writes it to the
+// store in batches. Writes happen on a background goroutine, so Add and
+// LogEvent never wait for the disk and may be called while holding the
+// simulation lock. A Recorder is not safe for concurrent use.
+type Recorder struct {
+	store     *Store
+	sessionID string
+	buffer    []models.TrajectoryPoint
+	queue     chan record
+	done      chan struct{}
+}
+
+// NewRecorder creates the session in the store and returns a recorder for it
+func NewRecorder(store *Store, session models.Session) (*Recorder, error) {
+	if err := store.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		store:     store,
+		sessionID: session.ID,
+		buffer:    make([]models.TrajectoryPoint, 0, flushSize),
+		queue:     make(chan record, writeQueue),
+		done:      make(chan struct{}),
+	}
+	go r.write()
+	return r, nil
+}
+
+// write stores queued records until the queue is closed
+func (r *Recorder) write() {
+	defer close(r.done)
+	for rec := range r.queue {
+		if rec.event != nil {
+			if err := r.store.AppendEvent(r.sessionID, *rec.event); err != nil {
+				log.Printf("Error logging %s event for session %s: %v", rec.event.Type, r.sessionID, err)
+			}
+			continue
+		}
+		if err := r.store.AppendTrajectory(r.sessionID, rec.points); err != nil {
+			log.Printf("Error recording trajectory for session %s: %v", r.sessionID, err)
+		}
+	}
+}
+
+// SessionID returns the ID of the session being recorded
+func (r *Recorder) SessionID() string {
+	return r.sessionID
+}
+
+// Add buffers a trajectory point, flushing when the buffer is full
+func (r *Recorder) Add(point models.TrajectoryPoint) {
+	r.buffer = append(r.buffer, point)
+	if len(r.buffer) >= flushSize {
+		r.Flush()
+	}
+}
+
+// Flush queues all buffered points to be written to the store
+func (r *Recorder) Flush() {
+	if len(r.buffer) == 0 {
+		return
+	}
+	r.queue <- record{points: r.buffer}
+	r.buffer = make([]models.TrajectoryPoint, 0, flushSize)
+}
+
+// LogEvent queues an event to be written to the session record. It never
+// blocks: when the writer has fallen behind, the event is dropped and only
+// logged.
+func (r *Recorder) LogEvent(event models.SessionEvent) {
+	select {
+	case r.queue <- record{event: &event}:
+	default:
+		log.Printf("Dropped %s event for session %s: the writer is behind", event.Type, r.sessionID)
+	}
+}
+
+// Close writes remaining points and marks the session as ended. It waits
+// for the writer, so call it without holding the simulation lock.
+func (r *Recorder) Close(endedAt time.Time) {
+	r.Flush()
+	close(r.queue)
+	<-r.done
+	if err := r.store.EndSession(r.sessionID, endedAt); err != nil {
+		log.Printf("Error ending session %s: %v", r.sessionID, err)
+	}
+}
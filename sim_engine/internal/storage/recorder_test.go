@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// openTestStore opens a store in a temporary directory
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestRecorderWritesEveryPointInOrder(t *testing.T) {
+	store := openTestStore(t)
+	recorder, err := NewRecorder(store, models.Session{ID: "s1", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+
+	// Several full batches and a partial one
+	const total = 3*flushSize + 17
+	for i := 0; i < total; i++ {
+		recorder.Add(models.TrajectoryPoint{TrueX: float64(i)})
+	}
+	endedAt := time.Unix(1000, 0).UTC()
+	recorder.Close(endedAt)
+
+	points, err := store.Trajectory("s1")
+	if err != nil {
+		t.Fatalf("Trajectory: %v", err)
+	}
+	if len(points) != total {
+		t.Fatalf("recorded %d points, want %d", len(points), total)
+	}
+	for i, point := range points {
+		if point.TrueX != float64(i) {
+			t.Fatalf("point %d has TrueX %g; points are out of order", i, point.TrueX)
+		}
+	}
+
+	session, err := store.GetSession("s1")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if session.EndedAt == nil || !session.EndedAt.Equal(endedAt) {
+		t.Errorf("EndedAt = %v, want %v", session.EndedAt, endedAt)
+	}
+}
+
+func TestRecorderCloseWithoutPoints(t *testing.T) {
+	store := openTestStore(t)
+	recorder, err := NewRecorder(store, models.Session{ID: "empty", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	recorder.Close(time.Now())
+
+	points, err := store.Trajectory("empty")
+	if err != nil {
+		t.Fatalf("Trajectory: %v", err)
+	}
+	if len(points) != 0 {
+		t.Errorf("recorded %d points, want none", len(points))
+	}
+}
+
+func TestRecorderWritesEvents(t *testing.T) {
+	store := openTestStore(t)
+	recorder, err := NewRecorder(store, models.Session{ID: "events", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	recorder.Add(models.TrajectoryPoint{})
+	recorder.LogEvent(models.SessionEvent{Type: models.MsgTypeWatchdogTripped, RobotID: "robot1"})
+	recorder.LogEvent(models.SessionEvent{Type: models.MsgTypeWatchdogTripped, RobotID: "robot2"})
+	recorder.Close(time.Now())
+
+	session, err := store.GetSession("events")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if len(session.Events) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(session.Events))
+	}
+	if session.Events[0].RobotID != "robot1" || session.Events[1].RobotID != "robot2" {
+		t.Errorf("events are out of order: %+v", session.Events)
+	}
+}
+
+func TestRecorderLogEventDoesNotBlock(t *testing.T) {
+	store := openTestStore(t)
+	recorder, err := NewRecorder(store, models.Session{ID: "stalled", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+
+	// Holding a write transaction stalls the writer
+	tx, err := store.db.Begin(true)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	logged := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*writeQueue; i++ {
+			recorder.LogEvent(models.SessionEvent{Type: models.MsgTypeWatchdogTripped})
+		}
+		close(logged)
+	}()
+	select {
+	case <-logged:
+	case <-time.After(time.Second):
+		t.Error("LogEvent blocked on a stalled writer")
+	}
+
+	tx.Rollback()
+	<-logged
+	recorder.Close(time.Now())
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	sessionsBucket     = []byte("sessions")
+	trajectoriesBucket = []byte("trajectories")
+)
+
+// ErrNotFound is returned when a session does not exist
+var ErrNotFound = errors.New("session not found")
+
+// Store persists sessions and their trajectories in an embedded BoltDB file
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the store at path
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{sessionsBucket, trajectoriesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing store %s: %w", path, err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database file
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// CreateSession records a new session
+func (s *Store) CreateSession(session models.Session) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.Bucket(trajectoriesBucket).CreateBucketIfNotExists([]byte(session.ID)); err != nil {
+			return err
+		}
+		return putSession(tx, session)
+	})
+}
+
+// EndSession marks a session as ended at endedAt
+func (s *Store) EndSession(id string, endedAt time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		session, err := getSession(tx, id)
+		if err != nil {
+			return err
+		}
+		session.EndedAt = &endedAt
+		return putSession(tx, session)
+	})
+}
+
+// AppendEvent logs an event to a session record
+func (s *Store) AppendEvent(id string, event models.SessionEvent) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		session, err := getSession(tx, id)
+		if err != nil {
+			return err
+		}
+		session.Events = append(session.Events, event)
+		return putSession(tx, session)
+	})
+}
+
+// GetSession returns a single session
+func (s *Store) GetSession(id string) (models.Session, error) {
+	var session models.Session
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		session, err = getSession(tx, id)
+		return err
+	})
+	return session, err
+}
+
+// ListSessions returns all sessions, newest first
+func (s *Store) ListSessions() ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).ForEach(func(_, v []byte) error {
+			var session models.Session
+			if err := json.Unmarshal(v, &session); err != nil {
+				return err
+			}
+			sessions = append(sessions, session)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// AppendTrajectory appends points to a session's trajectory in order
+func (s *Store) AppendTrajectory(id string, points []models.TrajectoryPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(trajectoriesBucket).Bucket([]byte(id))
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		for _, point := range points {
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(point)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(sequenceKey(seq), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Trajectory returns every recorded point of a session in order
+func (s *Store) Trajectory(id string) ([]models.TrajectoryPoint, error) {
+	points := []models.TrajectoryPoint{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(trajectoriesBucket).Bucket([]byte(id))
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		return bucket.ForEach(func(_, v []byte) error {
+			var point models.TrajectoryPoint
+			if err := json.Unmarshal(v, &point); err != nil {
+				return err
+			}
+			points = append(points, point)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func getSession(tx *bolt.Tx, id string) (models.Session, error) {
+	var session models.Session
+	data := tx.Bucket(sessionsBucket).Get([]byte(id))
+	if data == nil {
+		return session, ErrNotFound
+	}
+	err := json.Unmarshal(data, &session)
+	return session, err
+}
+
+func putSession(tx *bolt.Tx, session models.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(sessionsBucket).Put([]byte(session.ID), data)
+}
+
+// sequenceKey encodes seq big-endian so keys iterate in insertion order
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
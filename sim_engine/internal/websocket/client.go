@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -34,12 +35,15 @@ var upgrader = websocket.Upgrader{
 
 // Client represents a WebSocket client connection
 type Client struct {
+	ID   string // Identifies the client to others in its room
 	hub  *Hub
+	room *Room // Room the client is in; only changed by its readPump
 	conn *websocket.Conn
 	send chan []byte
 }
 
-// ServeWs handles WebSocket requests from clients
+// ServeWs handles WebSocket requests from clients. The room query parameter
+// joins or creates a room; without it the client joins the default room.
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -47,13 +51,19 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	roomID := r.URL.Query().Get("room")
+	if roomID == "" {
+		roomID = DefaultRoomID
+	}
 	client := &Client{
+		ID:   uuid.New().String()[:8],
 		hub:  hub,
 		conn: conn,
 		send: make(chan []byte, 256),
 	}
+	client.room, _ = hub.enterRoom(roomID, client, true, true)
 
-	client.hub.register <- client
+	client.hub.register <- membership{client: client, room: client.room}
 
 	// Start goroutines for reading and writing
 	go client.writePump()
@@ -64,6 +74,7 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
+		c.hub.leaveRoom(c.room, c)
 		c.conn.Close()
 	}()
 
@@ -0,0 +1,102 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/storage"
+)
+
+// testTimeout bounds how long a test waits for an expected message
+const testTimeout = 3 * time.Second
+
+// newTestHub starts a hub recording to store, which may be nil
+func newTestHub(t *testing.T, store *storage.Store) *Hub {
+	t.Helper()
+	h := NewHub(store)
+	go h.Run()
+	return h
+}
+
+// connect adds a client without a connection to a room, creating the room
+// when it does not exist. Messages to the client queue on its send channel.
+func connect(t *testing.T, h *Hub, roomID, clientID string) *Client {
+	t.Helper()
+	client := &Client{
+		ID:   clientID,
+		hub:  h,
+		send: make(chan []byte, 4096),
+	}
+	room, err := h.enterRoom(roomID, client, true, true)
+	if err != nil {
+		t.Fatalf("enterRoom(%q): %v", roomID, err)
+	}
+	client.room = room
+	h.register <- membership{client: client, room: room}
+	expect(t, client, models.MsgTypeRoomJoined, nil)
+	return client
+}
+
+// send handles a message from a client as if it arrived over its connection
+func send(t *testing.T, client *Client, msgType string, payload interface{}) {
+	t.Helper()
+	data, err := json.Marshal(models.WSMessage{Type: msgType, Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.hub.HandleMessage(client, data)
+}
+
+// expect reads messages sent to a client until one of the given type for
+// which match returns true, and decodes its payload into v. A nil match
+// accepts the first message of the type; a nil v skips decoding.
+func expect(t *testing.T, client *Client, msgType string, match func(models.WSMessage) bool, v ...interface{}) models.WSMessage {
+	t.Helper()
+	deadline := time.After(testTimeout)
+	for {
+		select {
+		case data, ok := <-client.send:
+			if !ok {
+				t.Fatalf("client %s was disconnected waiting for %s", client.ID, msgType)
+			}
+			var msg models.WSMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("invalid message %s: %v", data, err)
+			}
+			if msg.Type != msgType || (match != nil && !match(msg)) {
+				continue
+			}
+			for _, target := range v {
+				if err := decodePayload(msg.Payload, target); err != nil {
+					t.Fatalf("decoding %s payload: %v", msgType, err)
+				}
+			}
+			return msg
+		case <-deadline:
+			t.Fatalf("client %s received no matching %s message", client.ID, msgType)
+		}
+	}
+}
+
+// payloadIs returns a match that decodes a payload and tests it
+func payloadIs[T any](test func(T) bool) func(models.WSMessage) bool {
+	return func(msg models.WSMessage) bool {
+		var payload T
+		if err := decodePayload(msg.Payload, &payload); err != nil {
+			return false
+		}
+		return test(payload)
+	}
+}
+
+// expectError waits for an error message with the given code
+func expectError(t *testing.T, client *Client, code string) models.ErrorPayload {
+	t.Helper()
+	var payload models.ErrorPayload
+	expect(t, client, models.MsgTypeError, payloadIs(func(p models.ErrorPayload) bool {
+		return p.Code == code
+	}), &payload)
+	return payload
+}
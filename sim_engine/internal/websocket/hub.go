@@ -2,63 +2,124 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
-	"github.com/amogh1216/robot-vis/sim_engine/internal/simulation"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/storage"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
 	"github.com/google/uuid"
 )
 
-// Hub maintains active clients and broadcasts messages
+// DefaultRoomID is the room clients join when they do not name one. It is
+// never collected, so single-room clients and the REST API always find it.
+const DefaultRoomID = "default"
+
+// roomIdleTimeout is how long an empty room is kept before it is collected,
+// so a client that reloads the page can rejoin its room
+const roomIdleTimeout = 30 * time.Second
+
+// ErrRoomNotFound is returned when an ID names no open room
+var ErrRoomNotFound = errors.New("room not found")
+
+// ErrRoomDriven is reported when the REST API tries to change a room that a
+// client has control of
+var ErrRoomDriven = errors.New("room is controlled by a client")
+
+// roomMessage is a message for every client in one room
+type roomMessage struct {
+	room *Room
+	data []byte
+}
+
+// membership places a client in a room
+type membership struct {
+	client *Client
+	room   *Room
+}
+
+// Hub maintains active clients and the simulation rooms they are in
 type Hub struct {
-	// Registered clients
-	clients map[*Client]bool
+	// Registered clients and the room each one is in
+	clients map[*Client]*Room
 
-	// Inbound messages from clients
-	broadcast chan []byte
+	// Messages to broadcast to the clients of a room
+	broadcast chan roomMessage
 
 	// Register requests from clients
-	register chan *Client
+	register chan membership
+
+	// Room changes of registered clients
+	join chan membership
 
 	// Unregister requests from clients
 	unregister chan *Client
 
-	// Simulation engine
-	engine *simulation.Engine
+	// Open rooms by ID
+	rooms map[string]*Room
 
-	// Simulation loop control
-	running   bool
-	stopChan  chan struct{}
-	sessionID string
+	// World that new rooms start with
+	world *world.World
 
-	// Mutex for thread-safe operations
-	mu sync.RWMutex
+	// Session persistence (nil when disabled)
+	store *storage.Store
+
+	// Guards rooms, world and the clients and driver of every room
+	mu sync.Mutex
 }
 
-// NewHub creates a new Hub
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
+// NewHub creates a new Hub. Sessions are recorded to store unless it is nil.
+func NewHub(store *storage.Store) *Hub {
+	h := &Hub{
+		clients:    make(map[*Client]*Room),
+		broadcast:  make(chan roomMessage),
+		register:   make(chan membership),
+		join:       make(chan membership),
 		unregister: make(chan *Client),
-		engine:     simulation.NewEngine(),
-		running:    false,
-		stopChan:   make(chan struct{}),
+		rooms:      make(map[string]*Room),
+		store:      store,
 	}
+	h.rooms[DefaultRoomID] = newRoom(DefaultRoomID, h, nil)
+	return h
+}
+
+// SetWorld sets the world new rooms start with and loads it into the
+// default room
+func (h *Hub) SetWorld(w *world.World) {
+	h.mu.Lock()
+	h.world = w
+	room := h.rooms[DefaultRoomID]
+	h.mu.Unlock()
+
+	room.mu.Lock()
+	room.fleet.SetWorld(w)
+	room.mu.Unlock()
 }
 
 // Run starts the hub's main loop
 func (h *Hub) Run() {
+	collect := time.NewTicker(roomIdleTimeout / 2)
+	defer collect.Stop()
+
 	for {
 		select {
-		case client := <-h.register:
-			h.clients[client] = true
-			log.Printf("Client connected. Total clients: %d", len(h.clients))
-			// Send current state to new client
-			h.sendStateToClient(client)
+		case m := <-h.register:
+			h.clients[m.client] = m.room
+			log.Printf("Client connected to room %s. Total clients: %d", m.room.ID, len(h.clients))
+			// Send the room and its current state to the new client
+			h.sendRoomJoined(m.client, m.room)
+			m.room.sendStateToClient(m.client)
+
+		case m := <-h.join:
+			if _, ok := h.clients[m.client]; ok {
+				h.clients[m.client] = m.room
+				h.sendRoomJoined(m.client, m.room)
+				m.room.sendStateToClient(m.client)
+			}
 
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
@@ -68,304 +129,324 @@ func (h *Hub) Run() {
 			}
 
 		case message := <-h.broadcast:
-			for client := range h.clients {
+			for client, room := range h.clients {
+				if room != message.room {
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- message.data:
 				default:
 					close(client.send)
 					delete(h.clients, client)
 				}
 			}
+
+		case <-collect.C:
+			h.collectRooms()
 		}
 	}
 }
 
-// HandleMessage processes incoming WebSocket messages
-func (h *Hub) HandleMessage(client *Client, messageData []byte) {
-	log.Printf("Received raw message: %s", string(messageData))
-
-	var msg models.WSMessage
-	if err := json.Unmarshal(messageData, &msg); err != nil {
-		log.Printf("Error unmarshaling message: %v", err)
-		h.sendError(client, "INVALID_MESSAGE", "Failed to parse message")
-		return
+// collectRooms closes the rooms that have been empty for longer than
+// roomIdleTimeout
+func (h *Hub) collectRooms() {
+	h.mu.Lock()
+	var idle []*Room
+	for id, room := range h.rooms {
+		if id != DefaultRoomID && len(room.clients) == 0 && time.Since(room.emptySince) > roomIdleTimeout {
+			delete(h.rooms, id)
+			idle = append(idle, room)
+		}
 	}
+	h.mu.Unlock()
 
-	log.Printf("Parsed message type: %s", msg.Type)
-
-	switch msg.Type {
-	case models.MsgTypeWheelCommand:
-		h.handleWheelCommand(msg.Payload)
-
-	case models.MsgTypeUpdateConstants:
-		h.handleUpdateConstants(msg.Payload)
-
-	case models.MsgTypeStartSimulation:
-		h.handleStartSimulation()
-
-	case models.MsgTypeStopSimulation:
-		h.handleStopSimulation()
-
-	case models.MsgTypeResetSimulation:
-		h.handleResetSimulation()
-
-	default:
-		log.Printf("Unknown message type: %s", msg.Type)
-		h.sendError(client, "UNKNOWN_TYPE", "Unknown message type: "+msg.Type)
+	for _, room := range idle {
+		// Closing broadcasts a final status, which needs the hub loop
+		go room.close()
+		log.Printf("Room %s closed after being empty for %v", room.ID, roomIdleTimeout)
 	}
 }
 
-func (h *Hub) handleWheelCommand(payload interface{}) {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("Error marshaling wheel command: %v", err)
-		return
+// enterRoom adds a client to the room with the given ID. A missing room is
+// created when create is set and an open room is joined when join is set.
+// The client takes control of a room that has no driver.
+func (h *Hub) enterRoom(id string, client *Client, create, join bool) (*Room, error) {
+	h.mu.Lock()
+	room, ok := h.rooms[id]
+	switch {
+	case ok && !join:
+		h.mu.Unlock()
+		return nil, fmt.Errorf("room %q already exists", id)
+	case !ok && !create:
+		h.mu.Unlock()
+		return nil, fmt.Errorf("%w: %q", ErrRoomNotFound, id)
+	case !ok:
+		room = newRoom(id, h, h.world)
+		h.rooms[id] = room
+		log.Printf("Room %s created", id)
+	}
+	room.clients[client] = true
+	drives := room.driver == nil
+	if drives {
+		room.driver = client
 	}
+	h.mu.Unlock()
 
-	var cmd models.WheelCommand
-	if err := json.Unmarshal(data, &cmd); err != nil {
-		log.Printf("Error unmarshaling wheel command: %v", err)
-		return
+	if drives {
+		room.broadcastSimulationStatus()
 	}
+	return room, nil
+}
 
+// leaveRoom removes a client from a room, releasing control if it was the
+// driver
+func (h *Hub) leaveRoom(room *Room, client *Client) {
 	h.mu.Lock()
-	h.engine.SetWheelCommand(cmd)
+	delete(room.clients, client)
+	if len(room.clients) == 0 {
+		room.emptySince = time.Now()
+	}
+	released := room.driver == client
+	if released {
+		room.driver = nil
+	}
 	h.mu.Unlock()
-}
 
-func (h *Hub) handleUpdateConstants(payload interface{}) {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("Error marshaling constants: %v", err)
-		return
+	if released {
+		room.broadcastSimulationStatus()
 	}
+}
 
-	var constants models.RobotConstants
-	if err := json.Unmarshal(data, &constants); err != nil {
-		log.Printf("Error unmarshaling constants: %v", err)
-		return
+// driverID returns the client ID of a room's driver, empty when nobody has
+// control
+func (h *Hub) driverID(room *Room) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if room.driver == nil {
+		return ""
 	}
+	return room.driver.ID
+}
 
+// isDriver reports whether a client has control of its room
+func (h *Hub) isDriver(client *Client) bool {
 	h.mu.Lock()
-	h.engine.UpdateConstants(constants)
-	h.mu.Unlock()
+	defer h.mu.Unlock()
+	return client.room.driver == client
 }
 
-func (h *Hub) handleStartSimulation() {
-	h.mu.Lock()
-	if h.running {
-		h.mu.Unlock()
+// HandleMessage processes incoming WebSocket messages. Room changes are
+// handled by the hub; everything else goes to the client's room.
+func (h *Hub) HandleMessage(client *Client, messageData []byte) {
+	log.Printf("Received raw message: %s", string(messageData))
+
+	var msg models.WSMessage
+	if err := json.Unmarshal(messageData, &msg); err != nil {
+		log.Printf("Error unmarshaling message: %v", err)
+		sendError(client, "INVALID_MESSAGE", "Failed to parse message")
 		return
 	}
 
-	h.running = true
-	h.stopChan = make(chan struct{})
-	h.sessionID = uuid.New().String()
-	h.mu.Unlock()
+	log.Printf("Parsed message type: %s", msg.Type)
 
-	// Broadcast session created
-	h.broadcastMessage(models.WSMessage{
-		Type: models.MsgTypeSessionCreated,
-		Payload: map[string]string{
-			"sessionId": h.sessionID,
-		},
-	})
+	switch msg.Type {
+	case models.MsgTypeCreateRoom:
+		h.handleRoomChange(client, true, msg.Payload)
 
-	// Broadcast simulation status
-	h.broadcastSimulationStatus()
+	case models.MsgTypeJoinRoom:
+		h.handleRoomChange(client, false, msg.Payload)
 
-	// Start simulation loop
-	go h.simulationLoop()
+	case models.MsgTypeRequestControl:
+		h.handleRequestControl(client)
 
-	log.Printf("Simulation started with session ID: %s", h.sessionID)
+	case models.MsgTypeHandOverControl:
+		h.handleHandOverControl(client, msg.Payload)
+
+	default:
+		client.room.handleMessage(client, msg)
+	}
 }
 
-func (h *Hub) handleStopSimulation() {
-	h.mu.Lock()
-	if !h.running {
-		h.mu.Unlock()
+// handleRoomChange moves a client into a new room or an open one
+func (h *Hub) handleRoomChange(client *Client, create bool, payload interface{}) {
+	var req models.RoomPayload
+	if payload != nil {
+		if err := decodePayload(payload, &req); err != nil {
+			log.Printf("Error decoding room request: %v", err)
+			sendError(client, "INVALID_PAYLOAD", "Invalid room payload")
+			return
+		}
+	}
+	if req.RoomID == "" {
+		req.RoomID = DefaultRoomID
+		if create {
+			req.RoomID = uuid.New().String()[:8]
+		}
+	}
+	if req.RoomID == client.room.ID {
 		return
 	}
 
-	h.running = false
-	close(h.stopChan)
-	h.mu.Unlock()
+	room, err := h.enterRoom(req.RoomID, client, create, !create)
+	if err != nil {
+		code := "ROOM_EXISTS"
+		if errors.Is(err, ErrRoomNotFound) {
+			code = "ROOM_NOT_FOUND"
+		}
+		sendError(client, code, err.Error())
+		return
+	}
 
-	// Broadcast simulation status
-	h.broadcastSimulationStatus()
+	prev := client.room
+	client.room = room
+	h.join <- membership{client: client, room: room}
+	h.leaveRoom(prev, client)
 
-	log.Println("Simulation stopped")
+	log.Printf("Client %s moved from room %s to room %s", client.ID, prev.ID, room.ID)
 }
 
-func (h *Hub) handleResetSimulation() {
-	wasRunning := h.running
-	if wasRunning {
-		h.handleStopSimulation()
-	}
-
+// handleRequestControl gives control of the room to a client when nobody
+// has it, and otherwise asks the driver to hand it over
+func (h *Hub) handleRequestControl(client *Client) {
 	h.mu.Lock()
-	h.engine.Reset()
+	room := client.room
+	driver := room.driver
+	if driver == nil {
+		room.driver = client
+	}
 	h.mu.Unlock()
 
-	// Broadcast new state
-	h.broadcastState()
-
-	log.Println("Simulation reset")
+	switch driver {
+	case nil:
+		room.broadcastSimulationStatus()
+		log.Printf("Client %s took control of room %s", client.ID, room.ID)
+	case client:
+	default:
+		sendMessage(driver, models.WSMessage{
+			Type:    models.MsgTypeControlRequested,
+			Payload: models.ControlRequestedPayload{ClientID: client.ID},
+		})
+	}
 }
 
-// simulationLoop runs the simulation at fixed time steps
-func (h *Hub) simulationLoop() {
-	const targetFPS = 120
-	const dt = 1.0 / float64(targetFPS)
-	ticker := time.NewTicker(time.Duration(1000/targetFPS) * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-h.stopChan:
+// handleHandOverControl passes control from the driver to another client in
+// the room, or releases it
+func (h *Hub) handleHandOverControl(client *Client, payload interface{}) {
+	var req models.HandOverControlPayload
+	if payload != nil {
+		if err := decodePayload(payload, &req); err != nil {
+			log.Printf("Error decoding handOverControl: %v", err)
+			sendError(client, "INVALID_PAYLOAD", "Invalid handOverControl payload")
 			return
-		case <-ticker.C:
-			h.mu.Lock()
-			h.engine.Step(dt)
-			h.mu.Unlock()
-
-			// Broadcast state to all clients
-			h.broadcastState()
 		}
 	}
-}
 
-// broadcastState sends current state to all clients
-func (h *Hub) broadcastState() {
-	h.mu.RLock()
-	gt, odom := h.engine.GetState()
-	constants := h.engine.Constants
-	h.mu.RUnlock()
-
-	payload := models.StateUpdatePayload{
-		GroundTruth: gt,
-		Odometry:    odom,
-		Constants:   constants,
-		Timestamp:   time.Now().UnixMilli(),
+	h.mu.Lock()
+	room := client.room
+	driving := room.driver == client
+	var next *Client
+	for member := range room.clients {
+		if member.ID == req.ClientID {
+			next = member
+		}
 	}
+	found := req.ClientID == "" || next != nil
+	if driving && found {
+		room.driver = next
+	}
+	h.mu.Unlock()
 
-	h.broadcastMessage(models.WSMessage{
-		Type:    models.MsgTypeStateUpdate,
-		Payload: payload,
-	})
-}
-
-// broadcastSimulationStatus sends simulation status to all clients
-func (h *Hub) broadcastSimulationStatus() {
-	h.mu.RLock()
-	running := h.running
-	sessionID := h.sessionID
-	h.mu.RUnlock()
-
-	log.Printf("Broadcasting simulation status: running=%v, sessionID=%s", running, sessionID)
-
-	h.broadcastMessage(models.WSMessage{
-		Type: models.MsgTypeSimulationStatus,
-		Payload: models.SimulationStatusPayload{
-			Running:   running,
-			SessionID: sessionID,
-		},
-	})
-}
-
-// broadcastMessage sends a message to all connected clients
-func (h *Hub) broadcastMessage(msg models.WSMessage) {
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+	if !driving {
+		sendError(client, "FORBIDDEN", "Only the driver can hand over control")
 		return
 	}
+	if !found {
+		sendError(client, "CLIENT_NOT_FOUND", "No client "+req.ClientID+" in room "+room.ID)
+		return
+	}
+	room.broadcastSimulationStatus()
 
-	h.broadcast <- data
+	log.Printf("Client %s handed control of room %s to %q", client.ID, room.ID, req.ClientID)
 }
 
-// sendStateToClient sends current state to a specific client
-func (h *Hub) sendStateToClient(client *Client) {
-	h.mu.RLock()
-	gt, odom := h.engine.GetState()
-	constants := h.engine.Constants
-	running := h.running
-	sessionID := h.sessionID
-	h.mu.RUnlock()
-
-	// Send current state
-	stateMsg := models.WSMessage{
-		Type: models.MsgTypeStateUpdate,
-		Payload: models.StateUpdatePayload{
-			GroundTruth: gt,
-			Odometry:    odom,
-			Constants:   constants,
-			Timestamp:   time.Now().UnixMilli(),
+// sendRoomJoined tells a client which room it is in
+func (h *Hub) sendRoomJoined(client *Client, room *Room) {
+	sendMessage(client, models.WSMessage{
+		Type: models.MsgTypeRoomJoined,
+		Payload: models.RoomJoinedPayload{
+			RoomInfo: h.roomInfo(room),
+			ClientID: client.ID,
 		},
-	}
-
-	data, err := json.Marshal(stateMsg)
-	if err != nil {
-		log.Printf("Error marshaling state: %v", err)
-		return
-	}
+	})
+}
 
-	select {
-	case client.send <- data:
-	default:
+// roomInfo describes a room
+func (h *Hub) roomInfo(room *Room) models.RoomInfo {
+	h.mu.Lock()
+	clients := len(room.clients)
+	var driver string
+	if room.driver != nil {
+		driver = room.driver.ID
 	}
+	h.mu.Unlock()
 
-	// Send simulation status
-	statusMsg := models.WSMessage{
-		Type: models.MsgTypeSimulationStatus,
-		Payload: models.SimulationStatusPayload{
-			Running:   running,
-			SessionID: sessionID,
-		},
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	return models.RoomInfo{
+		RoomID:    room.ID,
+		Clients:   clients,
+		Running:   room.running,
+		SessionID: room.sessionID,
+		Driver:    driver,
 	}
+}
 
-	data, err = json.Marshal(statusMsg)
-	if err != nil {
-		log.Printf("Error marshaling status: %v", err)
-		return
+// Room returns the open room with the given ID; an empty ID is the default
+// room
+func (h *Hub) Room(id string) (*Room, error) {
+	if id == "" {
+		id = DefaultRoomID
 	}
 
-	select {
-	case client.send <- data:
-	default:
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room, ok := h.rooms[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrRoomNotFound, id)
 	}
+	return room, nil
 }
 
-// sendError sends an error message to a specific client
-func (h *Hub) sendError(client *Client, code, message string) {
-	msg := models.WSMessage{
-		Type: models.MsgTypeError,
-		Payload: models.ErrorPayload{
-			Code:    code,
-			Message: message,
-		},
-	}
-
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Error marshaling error message: %v", err)
-		return
+// Rooms describes every open room, ordered by ID
+func (h *Hub) Rooms() []models.RoomInfo {
+	h.mu.Lock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
 	}
+	h.mu.Unlock()
 
-	select {
-	case client.send <- data:
-	default:
+	infos := make([]models.RoomInfo, len(rooms))
+	for i, room := range rooms {
+		infos[i] = h.roomInfo(room)
 	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].RoomID < infos[j].RoomID
+	})
+	return infos
 }
 
-// GetEngine returns the simulation engine (for API handlers)
-func (h *Hub) GetEngine() *simulation.Engine {
-	return h.engine
+// GetStore returns the session store (nil when persistence is disabled)
+func (h *Hub) GetStore() *storage.Store {
+	return h.store
 }
 
-// IsRunning returns whether the simulation is running
+// IsRunning returns whether a simulation is running in any room
 func (h *Hub) IsRunning() bool {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return h.running
+	for _, info := range h.Rooms() {
+		if info.Running {
+			return true
+		}
+	}
+	return false
 }
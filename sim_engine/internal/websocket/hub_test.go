@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+func TestFirstClientDrivesAndObserversAreForbidden(t *testing.T) {
+	h := newTestHub(t, nil)
+	driver := connect(t, h, DefaultRoomID, "driver")
+	observer := connect(t, h, DefaultRoomID, "observer")
+
+	if id := h.driverID(driver.room); id != driver.ID {
+		t.Fatalf("driver = %q, want %q", id, driver.ID)
+	}
+	send(t, observer, models.MsgTypeWheelCommand, models.WheelCommand{LeftVelocity: 1, RightVelocity: 1})
+	expectError(t, observer, "FORBIDDEN")
+
+	command := driver.room.fleet.Primary().WheelCommand
+	if command.LeftVelocity != 0 || command.RightVelocity != 0 {
+		t.Errorf("an observer's wheel command was applied: %+v", command)
+	}
+}
+
+func TestRequestAndHandOverControl(t *testing.T) {
+	h := newTestHub(t, nil)
+	driver := connect(t, h, DefaultRoomID, "driver")
+	observer := connect(t, h, DefaultRoomID, "observer")
+
+	send(t, observer, models.MsgTypeRequestControl, nil)
+	var requested models.ControlRequestedPayload
+	expect(t, driver, models.MsgTypeControlRequested, nil, &requested)
+	if requested.ClientID != observer.ID {
+		t.Errorf("control requested by %q, want %q", requested.ClientID, observer.ID)
+	}
+	if id := h.driverID(driver.room); id != driver.ID {
+		t.Fatalf("requesting control took it from the driver; driver = %q", id)
+	}
+
+	send(t, observer, models.MsgTypeHandOverControl, models.HandOverControlPayload{ClientID: observer.ID})
+	expectError(t, observer, "FORBIDDEN")
+	send(t, driver, models.MsgTypeHandOverControl, models.HandOverControlPayload{ClientID: "nobody"})
+	expectError(t, driver, "CLIENT_NOT_FOUND")
+
+	send(t, driver, models.MsgTypeHandOverControl, models.HandOverControlPayload{ClientID: observer.ID})
+	expect(t, observer, models.MsgTypeSimulationStatus, payloadIs(func(p models.SimulationStatusPayload) bool {
+		return p.Driver == observer.ID
+	}))
+	send(t, driver, models.MsgTypeWheelCommand, models.WheelCommand{LeftVelocity: 1, RightVelocity: 1})
+	expectError(t, driver, "FORBIDDEN")
+
+	// Releasing control lets the next request take it
+	send(t, observer, models.MsgTypeHandOverControl, nil)
+	send(t, driver, models.MsgTypeRequestControl, nil)
+	if id := h.driverID(driver.room); id != driver.ID {
+		t.Errorf("driver = %q after release and request, want %q", id, driver.ID)
+	}
+}
+
+func TestRoomChanges(t *testing.T) {
+	h := newTestHub(t, nil)
+	client := connect(t, h, DefaultRoomID, "client")
+	other := connect(t, h, DefaultRoomID, "other")
+
+	send(t, client, models.MsgTypeCreateRoom, models.RoomPayload{RoomID: "lab"})
+	var joined models.RoomJoinedPayload
+	expect(t, client, models.MsgTypeRoomJoined, nil, &joined)
+	if joined.RoomID != "lab" || joined.Clients != 1 || joined.Driver != client.ID {
+		t.Errorf("joined %+v, want room lab with one client driving it", joined.RoomInfo)
+	}
+	// Leaving the default room released control of it
+	if id := h.driverID(other.room); id != "" {
+		t.Errorf("default room driver = %q after the driver left", id)
+	}
+
+	send(t, other, models.MsgTypeCreateRoom, models.RoomPayload{RoomID: "lab"})
+	expectError(t, other, "ROOM_EXISTS")
+	send(t, other, models.MsgTypeJoinRoom, models.RoomPayload{RoomID: "missing"})
+	expectError(t, other, "ROOM_NOT_FOUND")
+
+	send(t, other, models.MsgTypeJoinRoom, models.RoomPayload{RoomID: "lab"})
+	expect(t, other, models.MsgTypeRoomJoined, payloadIs(func(p models.RoomJoinedPayload) bool {
+		return p.RoomID == "lab" && p.Clients == 2 && p.Driver == client.ID
+	}))
+}
+
+func TestDriverChangesConstantsAndTwist(t *testing.T) {
+	h := newTestHub(t, nil)
+	driver := connect(t, h, DefaultRoomID, "driver")
+	room := driver.room
+	if !room.HasDriver() {
+		t.Fatal("the first client does not drive the room")
+	}
+
+	constants := models.DefaultRobotConstants()
+	constants.MaxSpeed = 0.5
+	send(t, driver, models.MsgTypeUpdateConstants, constants)
+	send(t, driver, models.MsgTypeTwistCommand, models.TwistCommand{LinearVel: 0.2})
+	var result models.TwistResult
+	expect(t, driver, models.MsgTypeTwistResult, nil, &result)
+	if result.Applied.LinearVel != 0.2 {
+		t.Errorf("applied %+v, want the requested twist", result.Applied)
+	}
+
+	room.mu.RLock()
+	robot := room.fleet.Primary()
+	maxSpeed, command := robot.Constants.MaxSpeed, robot.WheelCommand
+	room.mu.RUnlock()
+	if maxSpeed != 0.5 {
+		t.Errorf("max speed = %g, want the driver's 0.5", maxSpeed)
+	}
+	if command.LeftVelocity <= 0 || command.RightVelocity <= 0 {
+		t.Errorf("wheel command = %+v, want the driver's twist", command)
+	}
+
+	send(t, driver, models.MsgTypeHandOverControl, nil)
+	if room.HasDriver() {
+		t.Error("the room is still driven after the driver released control")
+	}
+}
@@ -0,0 +1,292 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/storage"
+)
+
+// replayFrameInterval matches the live simulation broadcast rate
+const replayFrameInterval = time.Second / 120
+
+// replayer streams the recorded frames of a session at their original timing
+type replayer struct {
+	room      *Room
+	sessionID string
+	constants models.RobotConstants
+	points    []models.TrajectoryPoint
+	offsets   []float64 // Seconds from the first point, per point
+
+	control chan models.ReplayControlPayload
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newReplayer(rm *Room, session models.Session, points []models.TrajectoryPoint) *replayer {
+	offsets := make([]float64, len(points))
+	for i, point := range points {
+		offsets[i] = point.Timestamp.Sub(points[0].Timestamp).Seconds()
+	}
+
+	return &replayer{
+		room:      rm,
+		sessionID: session.ID,
+		constants: session.Constants,
+		points:    points,
+		offsets:   offsets,
+		control:   make(chan models.ReplayControlPayload),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// duration returns the length of the recording in seconds
+func (r *replayer) duration() float64 {
+	return r.offsets[len(r.offsets)-1]
+}
+
+// run plays the recording until it is stopped. At the last frame it pauses
+// and stays on it, so the recording can still be sought or played again.
+func (r *replayer) run(speed float64) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(replayFrameInterval)
+	defer ticker.Stop()
+
+	position := 0.0
+	paused := false
+	finished := false
+	index := 0
+	last := time.Now()
+
+	r.sendFrame(index)
+	r.sendStatus(true, paused, finished, speed, position)
+
+	for {
+		select {
+		case <-r.stop:
+			r.sendStatus(false, paused, finished, speed, position)
+			return
+
+		case cmd := <-r.control:
+			switch cmd.Action {
+			case models.ReplayActionPause:
+				paused = true
+			case models.ReplayActionResume:
+				paused = false
+				if finished {
+					// Play the recording again from the start
+					finished = false
+					position = 0
+					index = 0
+					r.sendFrame(index)
+				}
+			case models.ReplayActionSpeed:
+				speed = cmd.Speed
+			case models.ReplayActionSeek:
+				position = clamp(cmd.Time, 0, r.duration())
+				index = r.indexAt(position)
+				finished = false
+				r.sendFrame(index)
+			}
+			last = time.Now()
+			r.sendStatus(true, paused, finished, speed, position)
+
+		case now := <-ticker.C:
+			if !paused {
+				position = min(position+now.Sub(last).Seconds()*speed, r.duration())
+			}
+			last = now
+
+			next := r.indexAt(position)
+			if next != index {
+				index = next
+				r.sendFrame(index)
+			}
+
+			if !finished && index == len(r.points)-1 {
+				finished = true
+				paused = true
+				r.sendStatus(true, paused, finished, speed, position)
+			}
+		}
+	}
+}
+
+// indexAt returns the last frame recorded at or before position
+func (r *replayer) indexAt(position float64) int {
+	i := sort.SearchFloat64s(r.offsets, position)
+	if i < len(r.offsets) && r.offsets[i] == position {
+		return i
+	}
+	if i == 0 {
+		return 0
+	}
+	return i - 1
+}
+
+// sendFrame broadcasts a recorded point as a stateUpdate
+func (r *replayer) sendFrame(i int) {
+	point := r.points[i]
+
+	r.room.broadcastMessage(models.WSMessage{
+		Type: models.MsgTypeStateUpdate,
+		Payload: models.StateUpdatePayload{
+			GroundTruth: models.RobotState{
+				X:          point.TrueX,
+				Y:          point.TrueY,
+				Theta:      point.TrueTheta,
+				LinearVel:  point.LinearVel,
+				AngularVel: point.AngularVel,
+				LeftWheel:  models.WheelState{Velocity: point.LeftWheelVel},
+				RightWheel: models.WheelState{Velocity: point.RightWheelVel},
+				Timestamp:  point.Timestamp,
+			},
+			Odometry: models.OdometryEstimate{
+				X:          point.OdomX,
+				Y:          point.OdomY,
+				Theta:      point.OdomTheta,
+				LeftWheel:  models.WheelState{Velocity: point.LeftWheelVel},
+				RightWheel: models.WheelState{Velocity: point.RightWheelVel},
+			},
+			Estimate:  point.Estimate,
+			Tracking:  point.Tracking,
+			Constants: r.constants,
+			SimTime:   r.offsets[i],
+			Timestamp: time.Now().UnixMilli(),
+		},
+	})
+}
+
+// sendStatus broadcasts the replay state
+func (r *replayer) sendStatus(active, paused, finished bool, speed, position float64) {
+	r.room.broadcastMessage(models.WSMessage{
+		Type: models.MsgTypeReplayStatus,
+		Payload: models.ReplayStatusPayload{
+			SessionID: r.sessionID,
+			Active:    active,
+			Paused:    paused,
+			Finished:  finished,
+			Speed:     speed,
+			Position:  position,
+			Duration:  r.duration(),
+		},
+	})
+}
+
+func (rm *Room) handleReplaySession(client *Client, payload interface{}) {
+	var req models.ReplaySessionPayload
+	if err := decodePayload(payload, &req); err != nil {
+		log.Printf("Error decoding replay request: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid replaySession payload")
+		return
+	}
+	if req.Speed <= 0 {
+		req.Speed = 1
+	}
+
+	if rm.store == nil {
+		sendError(client, "STORAGE_DISABLED", "Session storage is disabled")
+		return
+	}
+	if rm.IsRunning() {
+		sendError(client, "SIMULATION_RUNNING", "Stop the simulation before replaying a session")
+		return
+	}
+
+	session, err := rm.store.GetSession(req.SessionID)
+	if err == nil && session.EndedAt == nil {
+		err = errors.New("session is still being recorded")
+	}
+	var points []models.TrajectoryPoint
+	if err == nil {
+		points, err = rm.store.Trajectory(req.SessionID)
+	}
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && len(points) == 0) {
+		sendError(client, "SESSION_NOT_FOUND", "No recorded trajectory for session "+req.SessionID)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading session %s for replay: %v", req.SessionID, err)
+		sendError(client, "REPLAY_FAILED", err.Error())
+		return
+	}
+
+	rm.stopReplay()
+
+	r := newReplayer(rm, session, points)
+	rm.mu.Lock()
+	rm.replay = r
+	rm.mu.Unlock()
+
+	go r.run(req.Speed)
+
+	log.Printf("Replaying session %s (%d frames, %.1fx)", req.SessionID, len(points), req.Speed)
+}
+
+func (rm *Room) handleReplayControl(client *Client, payload interface{}) {
+	var cmd models.ReplayControlPayload
+	if err := decodePayload(payload, &cmd); err != nil {
+		log.Printf("Error decoding replay control: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid replayControl payload")
+		return
+	}
+
+	switch cmd.Action {
+	case models.ReplayActionStop:
+		rm.stopReplay()
+		return
+	case models.ReplayActionPause, models.ReplayActionResume, models.ReplayActionSeek:
+	case models.ReplayActionSpeed:
+		if cmd.Speed <= 0 {
+			sendError(client, "INVALID_PAYLOAD", "Replay speed must be positive")
+			return
+		}
+	default:
+		sendError(client, "INVALID_PAYLOAD", "Unknown replay action "+strconv.Quote(cmd.Action))
+		return
+	}
+
+	rm.mu.RLock()
+	r := rm.replay
+	rm.mu.RUnlock()
+
+	if r == nil {
+		sendError(client, "NO_REPLAY", "No replay is active")
+		return
+	}
+
+	select {
+	case r.control <- cmd:
+	case <-r.done:
+		sendError(client, "NO_REPLAY", "No replay is active")
+	}
+}
+
+// stopReplay stops the active replay, if any, and waits for it to finish
+func (rm *Room) stopReplay() {
+	rm.mu.Lock()
+	r := rm.replay
+	rm.replay = nil
+	rm.mu.Unlock()
+
+	if r == nil {
+		return
+	}
+
+	select {
+	case <-r.done:
+	default:
+		close(r.stop)
+		<-r.done
+	}
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/storage"
+)
+
+// recordedStore returns a store holding one ended session whose trajectory
+// lasts half a second
+func recordedStore(t *testing.T, sessionID string) *storage.Store {
+	t.Helper()
+	store, err := storage.Open(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("storage.Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	start := time.Unix(0, 0).UTC()
+	if err := store.CreateSession(models.Session{ID: sessionID, CreatedAt: start}); err != nil {
+		t.Fatal(err)
+	}
+	var points []models.TrajectoryPoint
+	for i := 0; i <= 30; i++ {
+		points = append(points, models.TrajectoryPoint{
+			Timestamp: start.Add(time.Duration(i) * time.Second / 60),
+			TrueX:     float64(i) / 60,
+		})
+	}
+	if err := store.AppendTrajectory(sessionID, points); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EndSession(sessionID, start.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestReplayPausesAtEnd(t *testing.T) {
+	h := newTestHub(t, recordedStore(t, "rec"))
+	client := connect(t, h, DefaultRoomID, "driver")
+	room := client.room
+
+	finished := payloadIs(func(p models.ReplayStatusPayload) bool { return p.Finished })
+	send(t, client, models.MsgTypeReplaySession, models.ReplaySessionPayload{SessionID: "rec", Speed: 4})
+
+	var status models.ReplayStatusPayload
+	expect(t, client, models.MsgTypeReplayStatus, finished, &status)
+	if !status.Active || !status.Paused || status.Position != status.Duration {
+		t.Errorf("status at the end = %+v; want an active replay paused at its duration", status)
+	}
+
+	room.mu.RLock()
+	r := room.replay
+	room.mu.RUnlock()
+	select {
+	case <-r.done:
+		t.Fatal("replay ended at the last frame")
+	default:
+	}
+
+	// Seeking back from the end leaves the replay paused at the new position
+	send(t, client, models.MsgTypeReplayControl, models.ReplayControlPayload{Action: models.ReplayActionSeek, Time: 0.1})
+	expect(t, client, models.MsgTypeReplayStatus, payloadIs(func(p models.ReplayStatusPayload) bool {
+		return !p.Finished && p.Paused && p.Position == 0.1
+	}))
+
+	// Resuming plays on to the end again
+	send(t, client, models.MsgTypeReplayControl, models.ReplayControlPayload{Action: models.ReplayActionResume})
+	expect(t, client, models.MsgTypeReplayStatus, finished)
+
+	// Seeking to the end finishes at once
+	send(t, client, models.MsgTypeReplayControl, models.ReplayControlPayload{Action: models.ReplayActionSeek, Time: 10})
+	expect(t, client, models.MsgTypeReplayStatus, finished)
+
+	send(t, client, models.MsgTypeReplayControl, models.ReplayControlPayload{Action: models.ReplayActionStop})
+	expect(t, client, models.MsgTypeReplayStatus, payloadIs(func(p models.ReplayStatusPayload) bool {
+		return !p.Active
+	}))
+	<-r.done
+}
+
+func TestReplayControlRejectsUnknownAction(t *testing.T) {
+	h := newTestHub(t, recordedStore(t, "rec"))
+	client := connect(t, h, DefaultRoomID, "driver")
+
+	send(t, client, models.MsgTypeReplaySession, models.ReplaySessionPayload{SessionID: "rec"})
+	expect(t, client, models.MsgTypeReplayStatus, nil)
+
+	send(t, client, models.MsgTypeReplayControl, models.ReplayControlPayload{Action: "rewind"})
+	expectError(t, client, "INVALID_PAYLOAD")
+
+	send(t, client, models.MsgTypeReplayControl, models.ReplayControlPayload{Action: models.ReplayActionSpeed})
+	expectError(t, client, "INVALID_PAYLOAD")
+
+	client.room.stopReplay()
+}
@@ -0,0 +1,990 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/planning"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/simulation"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/storage"
+	"github.com/amogh1216/robot-vis/sim_engine/internal/world"
+	"github.com/google/uuid"
+)
+
+// particleBroadcastRate is how often (Hz) the particle cloud is published.
+// Particle sets are large, so they are sent less often than stateUpdate.
+const particleBroadcastRate = 10
+
+// Simulation clock settings and their limits
+const (
+	defaultTargetFPS = 120
+	maxTargetFPS     = 1000
+	minDt            = 1e-4 // Seconds; bounds the steps taken per loop iteration
+	maxDt            = 0.1
+	minTimeScale     = 0.1
+	maxTimeScale     = 10.0
+	maxFrameSteps    = 1000 // Engine steps per loop iteration before owed time is dropped
+)
+
+// Room is one isolated simulation: its robots, world, simulation loop and
+// recording. Broadcasts reach only the clients that joined the room.
+type Room struct {
+	ID  string
+	hub *Hub
+
+	// Simulated robots and the world they share
+	fleet *simulation.Fleet
+
+	// Session persistence (nil when disabled)
+	store    *storage.Store
+	recorder *storage.Recorder
+
+	// Active replay of a recorded session (nil when not replaying)
+	replay *replayer
+
+	// Simulation loop control
+	running   bool
+	paused    bool
+	stopChan  chan struct{}
+	sessionID string
+
+	// Simulation clock: loop iterations per wall-clock second, the step
+	// length and simulated seconds per wall-clock second
+	targetFPS int
+	dt        float64
+	timeScale float64
+
+	// Wall-clock seconds without a command before the watchdog stops the
+	// robots, 0 when disabled
+	commandTimeout float64
+
+	// Mutex for thread-safe operations
+	mu sync.RWMutex
+
+	// Clients in the room, the one allowed to drive and when the last one
+	// left; guarded by hub.mu
+	clients    map[*Client]bool
+	driver     *Client
+	emptySince time.Time
+}
+
+// newRoom creates an idle room on the given world
+func newRoom(id string, hub *Hub, w *world.World) *Room {
+	fleet := simulation.NewFleet()
+	fleet.SetWorld(w)
+	return &Room{
+		ID:        id,
+		hub:       hub,
+		fleet:     fleet,
+		store:     hub.store,
+		clients:   make(map[*Client]bool),
+		running:   false,
+		stopChan:  make(chan struct{}),
+		targetFPS: defaultTargetFPS,
+		dt:        1.0 / defaultTargetFPS,
+		timeScale: 1,
+	}
+}
+
+// close stops the room's simulation and replay before it is discarded
+func (rm *Room) close() {
+	rm.stopReplay()
+	rm.handleStopSimulation()
+}
+
+// driverMessages are the messages that change the simulation, which only
+// the room's driver may send
+var driverMessages = map[string]bool{
+	models.MsgTypeWheelCommand:    true,
+	models.MsgTypeUpdateConstants: true,
+	models.MsgTypeStartSimulation: true,
+	models.MsgTypeStopSimulation:  true,
+	models.MsgTypeResetSimulation: true,
+	models.MsgTypeReplaySession:   true,
+	models.MsgTypeReplayControl:   true,
+	models.MsgTypeLoadWorld:       true,
+	models.MsgTypeMotorCommand:    true,
+	models.MsgTypeTwistCommand:    true,
+	models.MsgTypeNavigateTo:      true,
+	models.MsgTypeFollowPath:      true,
+	models.MsgTypeDWA:             true,
+	models.MsgTypeCancelControl:   true,
+	models.MsgTypeAddRobot:        true,
+	models.MsgTypeRemoveRobot:     true,
+
+	models.MsgTypePauseSimulation:  true,
+	models.MsgTypeResumeSimulation: true,
+	models.MsgTypeStepOnce:         true,
+	models.MsgTypeSetTimeScale:     true,
+}
+
+// handleMessage processes a message from a client in the room. Observers
+// may only watch; their simulation messages are rejected.
+func (rm *Room) handleMessage(client *Client, msg models.WSMessage) {
+	if driverMessages[msg.Type] && !rm.hub.isDriver(client) {
+		sendError(client, "FORBIDDEN", "Only the driver may send "+msg.Type+"; send requestControl first")
+		return
+	}
+
+	switch msg.Type {
+	case models.MsgTypeWheelCommand:
+		rm.handleWheelCommand(client, msg.RobotID, msg.Payload)
+
+	case models.MsgTypeUpdateConstants:
+		rm.handleUpdateConstants(client, msg.RobotID, msg.Payload)
+
+	case models.MsgTypeStartSimulation:
+		rm.handleStartSimulation(client, msg.Payload)
+
+	case models.MsgTypeStopSimulation:
+		rm.handleStopSimulation()
+
+	case models.MsgTypeResetSimulation:
+		rm.handleResetSimulation()
+
+	case models.MsgTypePauseSimulation:
+		rm.handlePauseSimulation(client, true)
+
+	case models.MsgTypeResumeSimulation:
+		rm.handlePauseSimulation(client, false)
+
+	case models.MsgTypeStepOnce:
+		rm.handleStepOnce(client, msg.Payload)
+
+	case models.MsgTypeSetTimeScale:
+		rm.handleSetTimeScale(client, msg.Payload)
+
+	case models.MsgTypeReplaySession:
+		rm.handleReplaySession(client, msg.Payload)
+
+	case models.MsgTypeReplayControl:
+		rm.handleReplayControl(client, msg.Payload)
+
+	case models.MsgTypeLoadWorld:
+		rm.handleLoadWorld(client, msg.Payload)
+
+	case models.MsgTypeMotorCommand:
+		rm.handleMotorCommand(client, msg.RobotID, msg.Payload)
+
+	case models.MsgTypeTwistCommand:
+		rm.handleTwistCommand(client, msg.RobotID, msg.Payload)
+
+	case models.MsgTypeNavigateTo:
+		rm.handleNavigateTo(client, msg.RobotID, msg.Payload)
+
+	case models.MsgTypeFollowPath:
+		rm.handleFollowPath(client, msg.RobotID, msg.Payload)
+
+	case models.MsgTypeDWA:
+		rm.handleDWA(client, msg.RobotID, msg.Payload)
+
+	case models.MsgTypeCancelControl:
+		rm.withRobot(client, msg.RobotID, func(robot *simulation.Robot) {
+			robot.CancelController("cancelled by client")
+		})
+
+	case models.MsgTypeAddRobot:
+		rm.handleAddRobot(client, msg.Payload)
+
+	case models.MsgTypeRemoveRobot:
+		rm.handleRemoveRobot(client, msg.Payload)
+
+	default:
+		log.Printf("Unknown message type: %s", msg.Type)
+		sendError(client, "UNKNOWN_TYPE", "Unknown message type: "+msg.Type)
+	}
+}
+
+// decodePayload converts a generic message payload into a typed struct
+func decodePayload(payload interface{}, v interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// withRobot runs fn under the lock on the robot a message addresses. An
+// unknown robot ID is reported to the client and fn is not run.
+func (rm *Room) withRobot(client *Client, robotID string, fn func(*simulation.Robot)) bool {
+	rm.mu.Lock()
+	robot, err := rm.fleet.Robot(robotID)
+	if err == nil {
+		fn(robot)
+	}
+	rm.mu.Unlock()
+
+	if err != nil {
+		sendError(client, "ROBOT_NOT_FOUND", err.Error())
+		return false
+	}
+	return true
+}
+
+func (rm *Room) handleWheelCommand(client *Client, robotID string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshaling wheel command: %v", err)
+		return
+	}
+
+	var cmd models.WheelCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		log.Printf("Error unmarshaling wheel command: %v", err)
+		return
+	}
+
+	rm.withRobot(client, robotID, func(robot *simulation.Robot) {
+		robot.SetWheelCommand(cmd)
+	})
+}
+
+func (rm *Room) handleTwistCommand(client *Client, robotID string, payload interface{}) {
+	var twist models.TwistCommand
+	if err := decodePayload(payload, &twist); err != nil {
+		log.Printf("Error decoding twist command: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid twistCommand payload")
+		return
+	}
+
+	result, err := rm.SetTwistCommand(robotID, twist)
+	if err != nil {
+		sendError(client, "ROBOT_NOT_FOUND", err.Error())
+		return
+	}
+	sendMessage(client, models.WSMessage{
+		Type:    models.MsgTypeTwistResult,
+		RobotID: robotID,
+		Payload: result,
+	})
+}
+
+func (rm *Room) handleNavigateTo(client *Client, robotID string, payload interface{}) {
+	var req models.NavigateToPayload
+	if err := decodePayload(payload, &req); err != nil {
+		log.Printf("Error decoding navigateTo: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid navigateTo payload")
+		return
+	}
+
+	controller, err := simulation.NewNavigator(req)
+	if err != nil {
+		sendError(client, "INVALID_PAYLOAD", err.Error())
+		return
+	}
+	rm.setController(client, robotID, controller)
+}
+
+func (rm *Room) handleFollowPath(client *Client, robotID string, payload interface{}) {
+	var req models.FollowPathPayload
+	if err := decodePayload(payload, &req); err != nil {
+		log.Printf("Error decoding followPath: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid followPath payload")
+		return
+	}
+
+	controller, err := simulation.NewPurePursuit(req)
+	if err != nil {
+		sendError(client, "INVALID_PAYLOAD", err.Error())
+		return
+	}
+	rm.setController(client, robotID, controller)
+}
+
+func (rm *Room) handleDWA(client *Client, robotID string, payload interface{}) {
+	var req models.DWAPayload
+	if err := decodePayload(payload, &req); err != nil {
+		log.Printf("Error decoding dwa: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid dwa payload")
+		return
+	}
+
+	controller, err := simulation.NewDWA(req)
+	if err != nil {
+		sendError(client, "INVALID_PAYLOAD", err.Error())
+		return
+	}
+	rm.setController(client, robotID, controller)
+}
+
+// setController hands a robot to an autonomous controller
+func (rm *Room) setController(client *Client, robotID string, controller simulation.Controller) {
+	var err error
+	rm.withRobot(client, robotID, func(robot *simulation.Robot) {
+		err = robot.SetController(controller)
+	})
+	if err != nil {
+		sendError(client, "FEEDBACK_UNAVAILABLE", err.Error())
+	}
+}
+
+func (rm *Room) handleMotorCommand(client *Client, robotID string, payload interface{}) {
+	var cmd models.MotorCommand
+	if err := decodePayload(payload, &cmd); err != nil {
+		log.Printf("Error decoding motor command: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid motorCommand payload")
+		return
+	}
+
+	var enabled bool
+	var err error
+	found := rm.withRobot(client, robotID, func(robot *simulation.Robot) {
+		enabled = robot.Constants.Motor != nil && robot.Constants.Motor.Enabled
+		if enabled {
+			err = robot.SetMotorCommand(cmd)
+		}
+	})
+
+	if !found {
+		return
+	} else if !enabled {
+		sendError(client, "MOTOR_MODEL_DISABLED", "Enable the motor model before sending motor commands")
+	} else if err != nil {
+		sendError(client, "INVALID_PAYLOAD", err.Error())
+	}
+}
+
+func (rm *Room) handleUpdateConstants(client *Client, robotID string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshaling constants: %v", err)
+		return
+	}
+
+	var constants models.RobotConstants
+	if err := json.Unmarshal(data, &constants); err != nil {
+		log.Printf("Error unmarshaling constants: %v", err)
+		return
+	}
+	if err := constants.Validate(); err != nil {
+		sendError(client, "INVALID_PAYLOAD", "Invalid constants: "+err.Error())
+		return
+	}
+
+	if err := rm.UpdateConstants(robotID, constants); err != nil {
+		sendError(client, "ROBOT_NOT_FOUND", err.Error())
+	}
+}
+
+func (rm *Room) handleAddRobot(client *Client, payload interface{}) {
+	var spec models.RobotSpec
+	if err := decodePayload(payload, &spec); err != nil {
+		log.Printf("Error decoding addRobot: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid addRobot payload")
+		return
+	}
+
+	rm.mu.Lock()
+	robot, err := rm.fleet.AddRobot(spec)
+	rm.mu.Unlock()
+
+	if err != nil {
+		sendError(client, "INVALID_ROBOT", err.Error())
+		return
+	}
+	rm.broadcastState()
+
+	log.Printf("Robot %s added at (%.2f, %.2f)", robot.ID, spec.Start.X, spec.Start.Y)
+}
+
+func (rm *Room) handleRemoveRobot(client *Client, payload interface{}) {
+	var req models.RemoveRobotPayload
+	if err := decodePayload(payload, &req); err != nil {
+		log.Printf("Error decoding removeRobot: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid removeRobot payload")
+		return
+	}
+
+	rm.mu.Lock()
+	err := rm.fleet.RemoveRobot(req.ID)
+	rm.mu.Unlock()
+
+	if err != nil {
+		sendError(client, "INVALID_ROBOT", err.Error())
+		return
+	}
+	rm.broadcastState()
+
+	log.Printf("Robot %s removed", req.ID)
+}
+
+func (rm *Room) handleLoadWorld(client *Client, payload interface{}) {
+	var w *world.World
+	if payload != nil {
+		var config models.World
+		if err := decodePayload(payload, &config); err != nil {
+			log.Printf("Error decoding world: %v", err)
+			sendError(client, "INVALID_PAYLOAD", "Invalid loadWorld payload")
+			return
+		}
+
+		var err error
+		if w, err = world.New(config); err != nil {
+			sendError(client, "INVALID_WORLD", err.Error())
+			return
+		}
+	}
+
+	rm.mu.Lock()
+	rm.fleet.SetWorld(w)
+	rm.mu.Unlock()
+
+	rm.broadcastMessage(rm.worldMessage())
+
+	log.Printf("World loaded (empty: %v)", w == nil)
+}
+
+func (rm *Room) handleStartSimulation(client *Client, payload interface{}) {
+	var start models.StartSimulationPayload
+	if payload != nil {
+		if err := decodePayload(payload, &start); err != nil {
+			log.Printf("Error decoding start payload: %v", err)
+			return
+		}
+	}
+	if start.TargetFPS < 0 || start.TargetFPS > maxTargetFPS {
+		sendError(client, "INVALID_PAYLOAD", fmt.Sprintf("targetFps must be between 1 and %d", maxTargetFPS))
+		return
+	}
+	if start.Dt != 0 && (start.Dt < minDt || start.Dt > maxDt) {
+		sendError(client, "INVALID_PAYLOAD", fmt.Sprintf("dt must be between %g and %g seconds", minDt, maxDt))
+		return
+	}
+
+	rm.stopReplay()
+
+	rm.mu.Lock()
+	if rm.running {
+		rm.mu.Unlock()
+		return
+	}
+
+	if start.Seed != 0 {
+		rm.fleet.SetSeed(start.Seed)
+	}
+	rm.commandTimeout = start.CommandTimeout
+	rm.fleet.SetCommandTimeout(rm.simCommandTimeout())
+	if start.TargetFPS != 0 {
+		rm.targetFPS = start.TargetFPS
+	}
+	if start.Dt != 0 {
+		rm.dt = start.Dt
+	}
+	rm.running = true
+	rm.paused = false
+	rm.stopChan = make(chan struct{})
+	stop := rm.stopChan
+	rm.sessionID = uuid.New().String()
+	rm.startRecording()
+	rm.mu.Unlock()
+
+	// Broadcast session created
+	rm.broadcastMessage(models.WSMessage{
+		Type: models.MsgTypeSessionCreated,
+		Payload: map[string]string{
+			"sessionId": rm.sessionID,
+		},
+	})
+
+	// Broadcast simulation status
+	rm.broadcastSimulationStatus()
+
+	// Start simulation loop
+	go rm.simulationLoop(stop)
+
+	log.Printf("Simulation started with session ID: %s, seed: %d, %d FPS, dt %gs",
+		rm.sessionID, rm.fleet.Primary().Seed(), rm.targetFPS, rm.dt)
+}
+
+func (rm *Room) handleStopSimulation() {
+	rm.mu.Lock()
+	if !rm.running {
+		rm.mu.Unlock()
+		return
+	}
+
+	rm.running = false
+	rm.paused = false
+	close(rm.stopChan)
+	recorder := rm.recorder
+	rm.recorder = nil
+	rm.mu.Unlock()
+
+	if recorder != nil {
+		recorder.Close(time.Now())
+	}
+
+	// Broadcast simulation status
+	rm.broadcastSimulationStatus()
+
+	log.Println("Simulation stopped")
+}
+
+func (rm *Room) handleResetSimulation() {
+	rm.stopReplay()
+	// Stopping checks under the lock whether the simulation is running
+	rm.handleStopSimulation()
+
+	rm.mu.Lock()
+	rm.fleet.Reset()
+	rm.mu.Unlock()
+
+	// Broadcast new state
+	rm.broadcastState()
+
+	log.Println("Simulation reset")
+}
+
+func (rm *Room) handlePauseSimulation(client *Client, pause bool) {
+	rm.mu.Lock()
+	running := rm.running
+	changed := running && rm.paused != pause
+	if changed {
+		rm.paused = pause
+	}
+	rm.mu.Unlock()
+
+	if !running {
+		sendError(client, "NOT_RUNNING", "Start the simulation before pausing it")
+		return
+	}
+	if changed {
+		rm.broadcastSimulationStatus()
+		log.Printf("Simulation paused: %v", pause)
+	}
+}
+
+func (rm *Room) handleStepOnce(client *Client, payload interface{}) {
+	var req models.StepOncePayload
+	if payload != nil {
+		if err := decodePayload(payload, &req); err != nil {
+			log.Printf("Error decoding stepOnce: %v", err)
+			sendError(client, "INVALID_PAYLOAD", "Invalid stepOnce payload")
+			return
+		}
+	}
+	if req.Dt != 0 && (req.Dt < minDt || req.Dt > maxDt) {
+		sendError(client, "INVALID_PAYLOAD", fmt.Sprintf("dt must be between %g and %g seconds", minDt, maxDt))
+		return
+	}
+
+	rm.mu.Lock()
+	if rm.running && !rm.paused {
+		rm.mu.Unlock()
+		sendError(client, "NOT_PAUSED", "Pause the simulation before stepping it")
+		return
+	}
+	dt := req.Dt
+	if dt == 0 {
+		dt = rm.dt
+	}
+	events := rm.step(dt)
+	particles := rm.particleMessages()
+	rm.mu.Unlock()
+
+	rm.broadcastState()
+	for _, event := range events {
+		rm.broadcastMessage(event)
+	}
+	for _, msg := range particles {
+		rm.broadcastMessage(msg)
+	}
+}
+
+func (rm *Room) handleSetTimeScale(client *Client, payload interface{}) {
+	var req models.TimeScalePayload
+	if err := decodePayload(payload, &req); err != nil {
+		log.Printf("Error decoding setTimeScale: %v", err)
+		sendError(client, "INVALID_PAYLOAD", "Invalid setTimeScale payload")
+		return
+	}
+	if req.Scale < minTimeScale || req.Scale > maxTimeScale {
+		sendError(client, "INVALID_PAYLOAD", fmt.Sprintf("scale must be between %g and %g", minTimeScale, maxTimeScale))
+		return
+	}
+
+	rm.mu.Lock()
+	rm.timeScale = req.Scale
+	rm.fleet.RescaleCommandTimeout(rm.simCommandTimeout())
+	rm.mu.Unlock()
+
+	rm.broadcastSimulationStatus()
+}
+
+// simCommandTimeout returns the command timeout in simulated seconds.
+// Clients send commands in wall-clock time while the watchdogs count
+// simulated time, so the timeout is scaled by the time scale. Must hold
+// rm.mu.
+func (rm *Room) simCommandTimeout() float64 {
+	return rm.commandTimeout * rm.timeScale
+}
+
+// step advances every robot by dt and returns the events raised. Must hold
+// rm.mu.
+func (rm *Room) step(dt float64) []models.WSMessage {
+	rm.fleet.Step(dt)
+	events := rm.fleet.DrainEvents()
+	if rm.recorder != nil {
+		rm.recorder.Add(rm.fleet.Primary().TrajectoryPoint())
+	}
+	rm.reportWatchdogTrips(events)
+	return events
+}
+
+// advance steps every robot through the simulated time owed in steps of dt,
+// and returns the events raised and the time still owed. It takes at most
+// maxFrameSteps steps and drops the rest, so a loop that cannot keep up runs
+// the simulation slower instead of holding the lock ever longer. Must hold
+// rm.mu.
+func (rm *Room) advance(owed float64) ([]models.WSMessage, float64) {
+	var events []models.WSMessage
+	// Tolerate rounding so a frame of exactly one step is not deferred
+	for steps := 0; owed >= rm.dt*(1-1e-9); steps++ {
+		if steps == maxFrameSteps {
+			return events, 0
+		}
+		events = append(events, rm.step(rm.dt)...)
+		owed -= rm.dt
+	}
+	return events, owed
+}
+
+// simulationLoop runs the simulation at the session's frame rate. Each frame
+// advances simulated time by timeScale/targetFPS in fixed steps of dt,
+// carrying any remainder over to the next frame, and broadcasts the result.
+// The loop ends when stop is closed. It is the stopChan of the run that
+// started it, taken under the lock, since a later start replaces stopChan.
+func (rm *Room) simulationLoop(stop <-chan struct{}) {
+	rm.mu.RLock()
+	targetFPS := rm.targetFPS
+	rm.mu.RUnlock()
+
+	particleEvery := max(targetFPS/particleBroadcastRate, 1)
+	ticker := time.NewTicker(time.Second / time.Duration(targetFPS))
+	defer ticker.Stop()
+
+	frames := 0
+	owed := 0.0 // Simulated seconds not yet stepped
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rm.mu.Lock()
+			if rm.paused {
+				rm.mu.Unlock()
+				continue
+			}
+			var events []models.WSMessage
+			events, owed = rm.advance(owed + rm.timeScale/float64(targetFPS))
+			var particles []models.WSMessage
+			if frames%particleEvery == 0 {
+				particles = rm.particleMessages()
+			}
+			frames++
+			rm.mu.Unlock()
+
+			// Broadcast state to all clients
+			rm.broadcastState()
+			for _, event := range events {
+				rm.broadcastMessage(event)
+			}
+			for _, msg := range particles {
+				rm.broadcastMessage(msg)
+			}
+		}
+	}
+}
+
+// reportWatchdogTrips gives the watchdog events the wall-clock timeout the
+// client set, and logs the robots they stopped to the session record. Must
+// hold rm.mu.
+func (rm *Room) reportWatchdogTrips(events []models.WSMessage) {
+	for i, event := range events {
+		if event.Type != models.MsgTypeWatchdogTripped {
+			continue
+		}
+		trip, _ := event.Payload.(models.WatchdogPayload)
+		trip.Timeout = rm.commandTimeout
+		events[i].Payload = trip
+		log.Printf("Watchdog stopped robot %s in room %s at %.2fs: no command for %.2fs",
+			event.RobotID, rm.ID, trip.SimTime, trip.Timeout)
+		if rm.recorder != nil {
+			rm.recorder.LogEvent(models.SessionEvent{
+				Type:    event.Type,
+				RobotID: event.RobotID,
+				Time:    time.Now(),
+				Payload: trip,
+			})
+		}
+	}
+}
+
+// particleMessages snapshots the particle cloud of every robot running a
+// particle filter. Must hold rm.mu.
+func (rm *Room) particleMessages() []models.WSMessage {
+	var msgs []models.WSMessage
+	for _, robot := range rm.fleet.Robots() {
+		if particles := robot.Particles(); particles != nil {
+			msgs = append(msgs, models.WSMessage{
+				Type:    models.MsgTypeParticles,
+				RobotID: robot.ID,
+				Payload: particles,
+			})
+		}
+	}
+	return msgs
+}
+
+// startRecording begins persisting the current session. The recording
+// follows the primary robot. Must hold rm.mu.
+func (rm *Room) startRecording() {
+	if rm.store == nil {
+		return
+	}
+
+	recorder, err := storage.NewRecorder(rm.store, models.Session{
+		ID:             rm.sessionID,
+		CreatedAt:      time.Now(),
+		Constants:      rm.fleet.Primary().Constants,
+		CommandTimeout: rm.commandTimeout,
+	})
+	if err != nil {
+		log.Printf("Error recording session %s: %v", rm.sessionID, err)
+		return
+	}
+	rm.recorder = recorder
+}
+
+// broadcastState sends current state to every client in the room
+func (rm *Room) broadcastState() {
+	rm.mu.RLock()
+	payload := rm.statePayload()
+	rm.mu.RUnlock()
+
+	rm.broadcastMessage(models.WSMessage{
+		Type:    models.MsgTypeStateUpdate,
+		Payload: payload,
+	})
+}
+
+// statePayload snapshots the state of every robot, with the primary robot
+// at the top level for single-robot clients. Must hold rm.mu.
+func (rm *Room) statePayload() models.StateUpdatePayload {
+	now := time.Now().UnixMilli()
+	robots := make([]models.StateUpdatePayload, 0, len(rm.fleet.Robots()))
+	for _, robot := range rm.fleet.Robots() {
+		gt, odom := robot.GetState()
+		robots = append(robots, models.StateUpdatePayload{
+			RobotID:     robot.ID,
+			GroundTruth: gt,
+			Odometry:    odom,
+			Estimate:    robot.Estimate(),
+			Encoders:    robot.Encoders(),
+			PID:         robot.PIDState(),
+			Tracking:    robot.Tracking(),
+			Constants:   robot.Constants,
+			SimTime:     robot.SimTime,
+			Timestamp:   now,
+		})
+	}
+
+	payload := robots[0]
+	payload.Robots = robots
+	return payload
+}
+
+// worldMessage describes the loaded world; an empty world clears the map
+func (rm *Room) worldMessage() models.WSMessage {
+	rm.mu.RLock()
+	w := rm.fleet.World
+	rm.mu.RUnlock()
+
+	config := models.World{Obstacles: []models.Obstacle{}}
+	if w != nil {
+		config = w.Config()
+	}
+	return models.WSMessage{Type: models.MsgTypeWorld, Payload: config}
+}
+
+// broadcastSimulationStatus sends simulation status to every client in the room
+func (rm *Room) broadcastSimulationStatus() {
+	rm.mu.RLock()
+	status := rm.statusPayload()
+	rm.mu.RUnlock()
+	status.Driver = rm.hub.driverID(rm)
+
+	log.Printf("Broadcasting simulation status: running=%v, sessionID=%s", status.Running, status.SessionID)
+
+	rm.broadcastMessage(models.WSMessage{
+		Type:    models.MsgTypeSimulationStatus,
+		Payload: status,
+	})
+}
+
+// statusPayload snapshots the simulation status, except for the driver,
+// which is guarded by the hub. Must hold rm.mu.
+func (rm *Room) statusPayload() models.SimulationStatusPayload {
+	return models.SimulationStatusPayload{
+		RoomID:    rm.ID,
+		Running:   rm.running,
+		SessionID: rm.sessionID,
+		Seed:      rm.fleet.Primary().Seed(),
+		Paused:    rm.paused,
+		TimeScale: rm.timeScale,
+		TargetFPS: rm.targetFPS,
+		Dt:        rm.dt,
+	}
+}
+
+// broadcastMessage sends a message to every client in the room
+func (rm *Room) broadcastMessage(msg models.WSMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	rm.hub.broadcast <- roomMessage{room: rm, data: data}
+}
+
+// sendStateToClient sends current state to a specific client
+func (rm *Room) sendStateToClient(client *Client) {
+	rm.mu.RLock()
+	payload := rm.statePayload()
+	status := rm.statusPayload()
+	rm.mu.RUnlock()
+	status.Driver = rm.hub.driverID(rm)
+
+	// Send current state
+	stateMsg := models.WSMessage{
+		Type:    models.MsgTypeStateUpdate,
+		Payload: payload,
+	}
+
+	data, err := json.Marshal(stateMsg)
+	if err != nil {
+		log.Printf("Error marshaling state: %v", err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+	}
+
+	// Send simulation status
+	statusMsg := models.WSMessage{
+		Type:    models.MsgTypeSimulationStatus,
+		Payload: status,
+	}
+
+	data, err = json.Marshal(statusMsg)
+	if err != nil {
+		log.Printf("Error marshaling status: %v", err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+	}
+
+	// Send the loaded world so the client can draw it
+	sendMessage(client, rm.worldMessage())
+}
+
+// sendMessage sends a message to a specific client
+func sendMessage(client *Client, msg models.WSMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", msg.Type, err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+	}
+}
+
+// sendError sends an error message to a specific client
+func sendError(client *Client, code, message string) {
+	msg := models.WSMessage{
+		Type: models.MsgTypeError,
+		Payload: models.ErrorPayload{
+			Code:    code,
+			Message: message,
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling error message: %v", err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+	}
+}
+
+// HasDriver reports whether a client has control of the room
+func (rm *Room) HasDriver() bool {
+	return rm.hub.driverID(rm) != ""
+}
+
+// UpdateConstants applies new robot constants between simulation steps. An
+// empty robot ID addresses the primary robot.
+func (rm *Room) UpdateConstants(robotID string, constants models.RobotConstants) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	robot, err := rm.fleet.Robot(robotID)
+	if err != nil {
+		return err
+	}
+	robot.UpdateConstants(constants)
+	return nil
+}
+
+// SetTwistCommand drives a robot with a robot velocity command. An empty
+// robot ID addresses the primary robot.
+func (rm *Room) SetTwistCommand(robotID string, twist models.TwistCommand) (models.TwistResult, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	robot, err := rm.fleet.Robot(robotID)
+	if err != nil {
+		return models.TwistResult{}, err
+	}
+	return robot.SetTwistCommand(twist), nil
+}
+
+// Plan plans a path across the current world for a robot. The search runs
+// outside the lock so it does not stall the simulation.
+func (rm *Room) Plan(robotID string, req models.PlanRequest) (models.PlanResponse, error) {
+	rm.mu.RLock()
+	robot, err := rm.fleet.Robot(robotID)
+	var constants models.RobotConstants
+	if err == nil {
+		constants = robot.Constants
+	}
+	w := rm.fleet.World
+	rm.mu.RUnlock()
+
+	if err != nil {
+		return models.PlanResponse{}, err
+	}
+	return planning.Plan(w, constants, req)
+}
+
+// IsRunning returns whether the simulation is running
+func (rm *Room) IsRunning() bool {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.running
+}
@@ -0,0 +1,127 @@
+package websocket
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+func TestUpdateConstantsRejectsInvalidLidar(t *testing.T) {
+	h := newTestHub(t, nil)
+	client := connect(t, h, DefaultRoomID, "driver")
+
+	c := models.DefaultRobotConstants()
+	c.Lidar.MaxRange = 0
+	send(t, client, models.MsgTypeUpdateConstants, c)
+	expectError(t, client, "INVALID_PAYLOAD")
+
+	robot := client.room.fleet.Primary()
+	if robot.Constants.Lidar.MaxRange != models.DefaultLidarConfig().MaxRange {
+		t.Errorf("invalid lidar config was applied: %+v", robot.Constants.Lidar)
+	}
+}
+
+func TestCommandTimeoutFollowsTimeScale(t *testing.T) {
+	h := newTestHub(t, nil)
+	client := connect(t, h, DefaultRoomID, "driver")
+	room := client.room
+	defer room.handleStopSimulation()
+
+	timeout := func() float64 {
+		room.mu.RLock()
+		defer room.mu.RUnlock()
+		return room.fleet.Primary().CommandTimeout()
+	}
+
+	send(t, client, models.MsgTypeSetTimeScale, models.TimeScalePayload{Scale: 2})
+	send(t, client, models.MsgTypeStartSimulation, models.StartSimulationPayload{CommandTimeout: 0.5})
+	if got := timeout(); got != 1 {
+		t.Errorf("watchdog timeout = %g simulated seconds at 2x, want 1", got)
+	}
+
+	send(t, client, models.MsgTypeSetTimeScale, models.TimeScalePayload{Scale: 0.5})
+	if got := timeout(); got != 0.25 {
+		t.Errorf("watchdog timeout = %g simulated seconds at 0.5x, want 0.25", got)
+	}
+}
+
+func TestWatchdogEventReportsWallClockTimeout(t *testing.T) {
+	h := newTestHub(t, nil)
+	client := connect(t, h, DefaultRoomID, "driver")
+	room := client.room
+	defer room.handleStopSimulation()
+
+	send(t, client, models.MsgTypeSetTimeScale, models.TimeScalePayload{Scale: 2})
+	send(t, client, models.MsgTypeStartSimulation, models.StartSimulationPayload{CommandTimeout: 0.5})
+	send(t, client, models.MsgTypePauseSimulation, nil)
+	send(t, client, models.MsgTypeWheelCommand, models.WheelCommand{LeftVelocity: 1, RightVelocity: 1})
+
+	var trips []models.WatchdogPayload
+	room.mu.Lock()
+	for i := 0; i < 150; i++ {
+		for _, event := range room.step(0.01) {
+			if event.Type == models.MsgTypeWatchdogTripped {
+				trips = append(trips, event.Payload.(models.WatchdogPayload))
+			}
+		}
+	}
+	room.mu.Unlock()
+
+	if len(trips) != 1 {
+		t.Fatalf("got %d watchdog events, want 1", len(trips))
+	}
+	if trips[0].Timeout != 0.5 {
+		t.Errorf("event timeout = %g, want the wall-clock 0.5", trips[0].Timeout)
+	}
+	// At 2x the robot stops after 1 simulated second
+	if age := trips[0].SimTime - trips[0].LastCommand; age < 1 || age > 1.02 {
+		t.Errorf("tripped after %g simulated seconds, want 1", age)
+	}
+}
+
+func TestAdvanceCapsStepsPerFrame(t *testing.T) {
+	h := newTestHub(t, nil)
+	client := connect(t, h, DefaultRoomID, "driver")
+	room := client.room
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	robot := room.fleet.Primary()
+	room.dt = 0.01
+
+	_, owed := room.advance(2.5 * room.dt)
+	if math.Abs(robot.SimTime-2*room.dt) > 1e-12 || math.Abs(owed-0.5*room.dt) > 1e-12 {
+		t.Errorf("stepped to %g s owing %g s, want two steps owing half of one", robot.SimTime, owed)
+	}
+
+	// Falling far behind steps at most maxFrameSteps and forgives the rest
+	start := robot.SimTime
+	_, owed = room.advance(100 * maxFrameSteps * room.dt)
+	if steps := math.Round((robot.SimTime - start) / room.dt); steps != maxFrameSteps {
+		t.Errorf("took %g steps, want %d", steps, maxFrameSteps)
+	}
+	if owed != 0 {
+		t.Errorf("still owing %g s after the cap, want 0", owed)
+	}
+}
+
+func TestRestartSimulation(t *testing.T) {
+	h := newTestHub(t, nil)
+	client := connect(t, h, DefaultRoomID, "driver")
+	room := client.room
+
+	// Each start replaces stopChan while the previous loop may still be
+	// winding down; run with -race to catch unguarded reads
+	for i := 0; i < 10; i++ {
+		send(t, client, models.MsgTypeStartSimulation, models.StartSimulationPayload{TargetFPS: 240})
+		send(t, client, models.MsgTypeStopSimulation, nil)
+	}
+	send(t, client, models.MsgTypeStartSimulation, models.StartSimulationPayload{TargetFPS: 240})
+	defer room.handleStopSimulation()
+
+	expect(t, client, models.MsgTypeStateUpdate, nil)
+	if !room.IsRunning() {
+		t.Error("simulation is not running after a restart")
+	}
+}
@@ -0,0 +1,364 @@
+package world
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+// segment is a wall segment from a to b
+type segment struct {
+	a, b models.Point
+}
+
+// circle is a circular obstacle
+type circle struct {
+	center models.Point
+	radius float64
+}
+
+// World is static obstacle geometry prepared for collision checks and ray
+// casting. It is immutable once built and safe for concurrent use.
+type World struct {
+	config   models.World
+	segments []segment
+	circles  []circle
+	polygons [][]models.Point
+}
+
+// New validates a world description and builds its geometry
+func New(config models.World) (*World, error) {
+	w := &World{config: config}
+
+	switch config.CollisionMode {
+	case "":
+		w.config.CollisionMode = models.CollisionSlide
+	case models.CollisionSlide, models.CollisionStop:
+	default:
+		return nil, fmt.Errorf("unknown collision mode %q", config.CollisionMode)
+	}
+
+	if b := config.Bounds; b != nil {
+		if b.MaxX <= b.MinX || b.MaxY <= b.MinY {
+			return nil, fmt.Errorf("bounds must have positive width and height")
+		}
+		corners := []models.Point{
+			{X: b.MinX, Y: b.MinY}, {X: b.MaxX, Y: b.MinY},
+			{X: b.MaxX, Y: b.MaxY}, {X: b.MinX, Y: b.MaxY},
+		}
+		w.addLoop(corners)
+	}
+
+	if config.Friction < 0 {
+		return nil, fmt.Errorf("friction must not be negative")
+	}
+	if config.Friction == 0 {
+		w.config.Friction = models.DefaultFriction
+	}
+	for i, z := range config.Terrain {
+		if z.Friction <= 0 {
+			return nil, fmt.Errorf("terrain zone %d: friction must be positive", i)
+		}
+		switch {
+		case z.Type == models.ObstacleCircle && z.Radius <= 0:
+			return nil, fmt.Errorf("terrain zone %d: circle radius must be positive", i)
+		case z.Type == models.ObstaclePolygon && len(z.Points) < 3:
+			return nil, fmt.Errorf("terrain zone %d: polygon needs at least 3 points", i)
+		case z.Type != models.ObstacleCircle && z.Type != models.ObstaclePolygon:
+			return nil, fmt.Errorf("terrain zone %d: unknown type %q", i, z.Type)
+		}
+	}
+
+	for i, o := range config.Obstacles {
+		switch o.Type {
+		case models.ObstacleCircle:
+			if o.Radius <= 0 {
+				return nil, fmt.Errorf("obstacle %d: circle radius must be positive", i)
+			}
+			w.circles = append(w.circles, circle{center: models.Point{X: o.X, Y: o.Y}, radius: o.Radius})
+		case models.ObstaclePolygon:
+			if len(o.Points) < 3 {
+				return nil, fmt.Errorf("obstacle %d: polygon needs at least 3 points", i)
+			}
+			w.polygons = append(w.polygons, o.Points)
+			w.addLoop(o.Points)
+		default:
+			return nil, fmt.Errorf("obstacle %d: unknown type %q", i, o.Type)
+		}
+	}
+
+	return w, nil
+}
+
+// Load reads a world description from a JSON file
+func Load(path string) (*World, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading world: %w", err)
+	}
+
+	var config models.World
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing world %s: %w", path, err)
+	}
+
+	w, err := New(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid world %s: %w", path, err)
+	}
+	return w, nil
+}
+
+// addLoop adds the closed chain of segments through points
+func (w *World) addLoop(points []models.Point) {
+	for i := range points {
+		w.segments = append(w.segments, segment{a: points[i], b: points[(i+1)%len(points)]})
+	}
+}
+
+// Config returns the world description the geometry was built from
+func (w *World) Config() models.World {
+	return w.config
+}
+
+// CollisionMode returns how the robot responds to contact
+func (w *World) CollisionMode() string {
+	return w.config.CollisionMode
+}
+
+// Bounds returns the boundary walls, or nil for an open plane
+func (w *World) Bounds() *models.Bounds {
+	return w.config.Bounds
+}
+
+// Friction returns the wheel-ground friction coefficient at (x, y)
+func (w *World) Friction(x, y float64) float64 {
+	p := models.Point{X: x, Y: y}
+	for i := len(w.config.Terrain) - 1; i >= 0; i-- {
+		z := w.config.Terrain[i]
+		inside := false
+		if z.Type == models.ObstacleCircle {
+			inside = dist(p, models.Point{X: z.X, Y: z.Y}) <= z.Radius
+		} else {
+			inside = insidePolygon(p, z.Points)
+		}
+		if inside {
+			return z.Friction
+		}
+	}
+	return w.config.Friction
+}
+
+// Distance returns the distance from (x, y) to the nearest obstacle surface.
+// Points inside a polygon or circle report zero.
+func (w *World) Distance(x, y float64) float64 {
+	p := models.Point{X: x, Y: y}
+	best := math.Inf(1)
+
+	for _, s := range w.segments {
+		best = math.Min(best, dist(p, closestOnSegment(p, s)))
+	}
+	for _, c := range w.circles {
+		best = math.Min(best, math.Max(0, dist(p, c.center)-c.radius))
+	}
+	for _, poly := range w.polygons {
+		if insidePolygon(p, poly) {
+			return 0
+		}
+	}
+	return best
+}
+
+// Collides reports whether a disc of the given radius at (x, y) overlaps any
+// obstacle or lies outside the bounds
+func (w *World) Collides(x, y, radius float64) bool {
+	if b := w.config.Bounds; b != nil {
+		if x-radius < b.MinX || x+radius > b.MaxX || y-radius < b.MinY || y+radius > b.MaxY {
+			return true
+		}
+	}
+	return w.Distance(x, y) < radius
+}
+
+// Resolve pushes a disc of the given radius at (x, y) out of every obstacle
+// it penetrates and back inside the bounds, so it agrees with Collides. It
+// returns the corrected position and, when contact occurred, the unit
+// contact normal pointing away from the obstacles.
+func (w *World) Resolve(x, y, radius float64) (rx, ry float64, hit bool, nx, ny float64) {
+	const iterations = 4
+	rx, ry = x, y
+
+	for iter := 0; iter < iterations; iter++ {
+		p := models.Point{X: rx, Y: ry}
+		moved := false
+
+		push := func(normalX, normalY, depth float64) {
+			rx += normalX * depth
+			ry += normalY * depth
+			p = models.Point{X: rx, Y: ry}
+			nx += normalX
+			ny += normalY
+			hit, moved = true, true
+		}
+
+		// The wall segments only push a disc that overlaps them, so a center
+		// beyond the bounds is brought back by clamping
+		if cx, cy := w.clampToBounds(rx, ry, radius); cx != rx || cy != ry {
+			dx, dy := cx-rx, cy-ry
+			d := math.Hypot(dx, dy)
+			push(dx/d, dy/d, d)
+		}
+
+		// A center inside a polygon is pushed out through the nearest edge
+		for _, poly := range w.polygons {
+			if !insidePolygon(p, poly) {
+				continue
+			}
+			best, bestDist := models.Point{}, math.Inf(1)
+			for i := range poly {
+				c := closestOnSegment(p, segment{a: poly[i], b: poly[(i+1)%len(poly)]})
+				if d := dist(p, c); d < bestDist {
+					best, bestDist = c, d
+				}
+			}
+			if bestDist > 0 {
+				push((best.X-p.X)/bestDist, (best.Y-p.Y)/bestDist, bestDist+radius)
+			}
+		}
+
+		for _, s := range w.segments {
+			c := closestOnSegment(p, s)
+			if d := dist(p, c); d < radius && d > 1e-12 {
+				push((p.X-c.X)/d, (p.Y-c.Y)/d, radius-d)
+			}
+		}
+
+		for _, c := range w.circles {
+			d := dist(p, c.center)
+			if d < radius+c.radius && d > 1e-12 {
+				push((p.X-c.center.X)/d, (p.Y-c.center.Y)/d, radius+c.radius-d)
+			}
+		}
+
+		if !moved {
+			break
+		}
+	}
+
+	// Obstacles near a wall may have pushed the disc back out on the last
+	// iteration; the bounds win
+	rx, ry = w.clampToBounds(rx, ry, radius)
+
+	if n := math.Hypot(nx, ny); n > 0 {
+		nx, ny = nx/n, ny/n
+	}
+	return rx, ry, hit, nx, ny
+}
+
+// clampToBounds returns the center nearest to (x, y) at which a disc of the
+// given radius lies inside the bounds. Bounds narrower than the disc center
+// it between the walls.
+func (w *World) clampToBounds(x, y, radius float64) (float64, float64) {
+	b := w.config.Bounds
+	if b == nil {
+		return x, y
+	}
+	return clampCenter(x, b.MinX+radius, b.MaxX-radius), clampCenter(y, b.MinY+radius, b.MaxY-radius)
+}
+
+// clampCenter clamps v into [lo, hi], or returns the midpoint when lo > hi
+func clampCenter(v, lo, hi float64) float64 {
+	if lo > hi {
+		return (lo + hi) / 2
+	}
+	return math.Max(lo, math.Min(v, hi))
+}
+
+// Raycast returns the distance from (x, y) along heading to the nearest
+// obstacle surface, or maxRange when nothing is hit within range
+func (w *World) Raycast(x, y, heading, maxRange float64) float64 {
+	dx, dy := math.Cos(heading), math.Sin(heading)
+	best := maxRange
+
+	for _, s := range w.segments {
+		if t, ok := raySegment(x, y, dx, dy, s); ok && t < best {
+			best = t
+		}
+	}
+	for _, c := range w.circles {
+		if t, ok := rayCircle(x, y, dx, dy, c); ok && t < best {
+			best = t
+		}
+	}
+	return best
+}
+
+// raySegment intersects the ray (x, y) + t(dx, dy), t ≥ 0, with a segment
+func raySegment(x, y, dx, dy float64, s segment) (float64, bool) {
+	ex, ey := s.b.X-s.a.X, s.b.Y-s.a.Y
+	denom := dx*ey - dy*ex
+	if math.Abs(denom) < 1e-12 {
+		return 0, false
+	}
+
+	ax, ay := s.a.X-x, s.a.Y-y
+	t := (ax*ey - ay*ex) / denom
+	u := (ax*dy - ay*dx) / denom
+	if t < 0 || u < 0 || u > 1 {
+		return 0, false
+	}
+	return t, true
+}
+
+// rayCircle intersects the ray (x, y) + t(dx, dy), t ≥ 0, with a circle
+func rayCircle(x, y, dx, dy float64, c circle) (float64, bool) {
+	ox, oy := x-c.center.X, y-c.center.Y
+	b := ox*dx + oy*dy
+	cc := ox*ox + oy*oy - c.radius*c.radius
+	disc := b*b - cc
+	if disc < 0 {
+		return 0, false
+	}
+
+	sq := math.Sqrt(disc)
+	if t := -b - sq; t >= 0 {
+		return t, true
+	}
+	if t := -b + sq; t >= 0 {
+		return t, true
+	}
+	return 0, false
+}
+
+// closestOnSegment returns the point of s nearest to p
+func closestOnSegment(p models.Point, s segment) models.Point {
+	ex, ey := s.b.X-s.a.X, s.b.Y-s.a.Y
+	lengthSq := ex*ex + ey*ey
+	if lengthSq == 0 {
+		return s.a
+	}
+
+	t := ((p.X-s.a.X)*ex + (p.Y-s.a.Y)*ey) / lengthSq
+	t = math.Max(0, math.Min(1, t))
+	return models.Point{X: s.a.X + t*ex, Y: s.a.Y + t*ey}
+}
+
+// insidePolygon reports whether p lies inside poly (even-odd rule)
+func insidePolygon(p models.Point, poly []models.Point) bool {
+	inside := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) &&
+			p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func dist(a, b models.Point) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
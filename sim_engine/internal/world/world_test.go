@@ -0,0 +1,120 @@
+package world
+
+import (
+	"math"
+	"testing"
+
+	"github.com/amogh1216/robot-vis/sim_engine/internal/models"
+)
+
+const epsilon = 1e-9
+
+// arena is a 6 m square with a circle and a square obstacle
+func arena(t *testing.T) *World {
+	t.Helper()
+	w, err := New(models.World{
+		Bounds: &models.Bounds{MinX: -3, MinY: -3, MaxX: 3, MaxY: 3},
+		Obstacles: []models.Obstacle{
+			{Type: models.ObstacleCircle, X: 1, Y: 0, Radius: 0.5},
+			{Type: models.ObstaclePolygon, Points: []models.Point{
+				{X: -2, Y: 1}, {X: -1, Y: 1}, {X: -1, Y: 2}, {X: -2, Y: 2},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return w
+}
+
+func TestResolveAgreesWithCollides(t *testing.T) {
+	w := arena(t)
+	const radius = 0.1
+
+	tests := []struct {
+		name string
+		x, y float64
+	}{
+		{"beyond the right wall", 3.5, 0},
+		{"beyond a corner", -4, 5},
+		{"overlapping the bottom wall", 0, -2.95},
+		{"inside the circle", 1.2, 0.1},
+		{"inside the square", -1.6, 1.5},
+		{"overlapping the square", -0.95, 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !w.Collides(tt.x, tt.y, radius) {
+				t.Fatalf("Collides(%g, %g) = false", tt.x, tt.y)
+			}
+			rx, ry, hit, nx, ny := w.Resolve(tt.x, tt.y, radius)
+			if !hit {
+				t.Errorf("Resolve(%g, %g) reported no contact", tt.x, tt.y)
+			}
+			if w.Collides(rx, ry, radius-epsilon) {
+				t.Errorf("Resolve(%g, %g) = (%g, %g), which still collides", tt.x, tt.y, rx, ry)
+			}
+			if n := math.Hypot(nx, ny); math.Abs(n-1) > epsilon {
+				t.Errorf("normal (%g, %g) is not a unit vector", nx, ny)
+			}
+		})
+	}
+}
+
+func TestResolveOutsideBounds(t *testing.T) {
+	w := arena(t)
+	rx, ry, hit, nx, ny := w.Resolve(3.5, 0, 0.1)
+	if !hit || math.Abs(rx-2.9) > epsilon || ry != 0 {
+		t.Errorf("Resolve(3.5, 0) = (%g, %g, %v), want (2.9, 0, true)", rx, ry, hit)
+	}
+	if math.Abs(nx+1) > epsilon || math.Abs(ny) > epsilon {
+		t.Errorf("normal = (%g, %g), want (-1, 0)", nx, ny)
+	}
+}
+
+func TestResolveFreeSpace(t *testing.T) {
+	w := arena(t)
+	rx, ry, hit, _, _ := w.Resolve(0, 0, 0.1)
+	if hit || rx != 0 || ry != 0 {
+		t.Errorf("Resolve in free space = (%g, %g, %v), want (0, 0, false)", rx, ry, hit)
+	}
+}
+
+func TestRaycast(t *testing.T) {
+	w := arena(t)
+
+	tests := []struct {
+		name                    string
+		x, y, heading, maxRange float64
+		want                    float64
+	}{
+		{"to the circle", 0, 0, 0, 10, 0.5},
+		{"to the top wall", 0, 0, math.Pi / 2, 10, 3},
+		{"to the square", -1.5, 0, math.Pi / 2, 10, 1},
+		{"beyond range", 0, 0, math.Pi / 2, 2, 2},
+		{"from inside the circle", 1, 0, 0, 10, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.Raycast(tt.x, tt.y, tt.heading, tt.maxRange); math.Abs(got-tt.want) > epsilon {
+				t.Errorf("Raycast = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidWorlds(t *testing.T) {
+	invalid := []models.World{
+		{Bounds: &models.Bounds{MinX: 1, MaxX: 1, MinY: 0, MaxY: 1}},
+		{Obstacles: []models.Obstacle{{Type: models.ObstacleCircle, Radius: 0}}},
+		{Obstacles: []models.Obstacle{{Type: models.ObstaclePolygon, Points: []models.Point{{}, {X: 1}}}}},
+		{Obstacles: []models.Obstacle{{Type: "triangle"}}},
+		{CollisionMode: "bounce"},
+		{Friction: -1},
+	}
+	for i, config := range invalid {
+		if _, err := New(config); err == nil {
+			t.Errorf("world %d: New accepted %+v", i, config)
+		}
+	}
+}